@@ -46,7 +46,7 @@ func main() {
 	// stupid delay to make sure things settle into state (? find a better way to do this)
 	time.Sleep(10 * time.Second)
 
-	state := s.State
+	state := s.State.Snapshot()
 	var b []byte
 	if prettyPrint {
 		b, err = json.MarshalIndent(state, "", "    ")
@@ -0,0 +1,173 @@
+// openttd_console is an interactive remote console for a server, over the admin port.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/chzyer/readline"
+	gopenttd "github.com/ropenttd/gopenttd/pkg/admin"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/rcon"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	serverHost  string
+	serverPort  int
+	serverPass  string
+	historyFile string
+	noColour    bool
+)
+
+func init() {
+	history := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		history = filepath.Join(home, ".openttd_console_history")
+	}
+
+	flag.StringVar(&serverHost, "target.host", "testserver.ttdredd.it", "Target host to connect to.")
+	flag.IntVar(&serverPort, "target.port", 3977, "Target port (this should be the admin port)")
+	flag.StringVar(&serverPass, "target.pass", "", "Target password")
+	flag.StringVar(&historyFile, "history", history, "File to keep the command history in, or empty to not keep it.")
+	flag.BoolVar(&noColour, "nocolour", false, "Don't colour the output.")
+	flag.Parse()
+}
+
+// colour returns the text in the given console colour.
+func colour(c enum.TextColour, text string) string {
+	if noColour {
+		return text
+	}
+	return c.ANSI(text)
+}
+
+// completer completes console commands, and setting names for the setting command.
+type completer struct {
+	commands []string
+	settings []string
+}
+
+// load fetches the names of the console commands and settings from the server.
+func (c *completer) load(s *gopenttd.Session) {
+	for _, list := range []string{"list_cmds", "list_aliases"} {
+		output, err := s.RconSync(list)
+		if err != nil {
+			log.Warnf("couldn't list console commands: %s", err)
+			return
+		}
+		lines := make([]string, len(output))
+		for i, l := range output {
+			lines[i] = l.Output
+		}
+		commands, _ := rcon.ParseCommandList(lines)
+		c.commands = append(c.commands, commands...)
+	}
+	sort.Strings(c.commands)
+
+	settings, err := s.ListSettings(context.Background(), "")
+	if err != nil {
+		log.Warnf("couldn't list settings: %s", err)
+		return
+	}
+	for _, st := range settings {
+		c.settings = append(c.settings, st.Name)
+	}
+}
+
+// Do implements readline.AutoCompleter.
+func (c *completer) Do(line []rune, pos int) (suggestions [][]rune, length int) {
+	words := strings.Fields(string(line[:pos]))
+	if len(words) == 0 || strings.HasSuffix(string(line[:pos]), " ") {
+		words = append(words, "")
+	}
+
+	var candidates []string
+	switch {
+	case len(words) == 1:
+		candidates = c.commands
+	case len(words) == 2 && words[0] == "setting":
+		candidates = c.settings
+	default:
+		return nil, 0
+	}
+
+	word := words[len(words)-1]
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			suggestions = append(suggestions, []rune(candidate[len(word):]+" "))
+		}
+	}
+	return suggestions, len([]rune(word))
+}
+
+func main() {
+	s, err := gopenttd.New(serverHost, serverPort, serverPass)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s.StateEnabled = false
+
+	err = s.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	c := &completer{}
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:          fmt.Sprintf("%s> ", serverHost),
+		HistoryFile:     historyFile,
+		AutoComplete:    c,
+		InterruptPrompt: "^C",
+		EOFPrompt:       "exit",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rl.Close()
+	out := rl.Stdout()
+	log.SetOutput(rl.Stderr())
+
+	// Show what's happening on the server's console while we wait for commands
+	s.AddHandler(func(s *gopenttd.Session, m *gopenttd.Console) {
+		fmt.Fprintln(out, colour(enum.TextColourGrey, "["+m.Origin+"]"), colour(enum.ConsoleColourDefault, m.Message))
+	})
+	s.RequestUpdates(enum.UpdateTypeConsole, enum.UpdateFrequencyAutomatically)
+
+	c.load(s)
+
+	for {
+		line, err := rl.Readline()
+		if err == readline.ErrInterrupt {
+			continue
+		} else if err == io.EOF {
+			return
+		} else if err != nil {
+			log.Fatal(err)
+		}
+
+		line = strings.TrimSpace(line)
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.RconTimeout)
+		output := s.RconStream(ctx, line)
+		for l := range output.Lines {
+			fmt.Fprintln(out, colour(l.Colour, l.Output))
+		}
+		if err := output.Err(); err != nil {
+			fmt.Fprintln(out, colour(enum.ConsoleColourError, err.Error()))
+		}
+		cancel()
+	}
+}
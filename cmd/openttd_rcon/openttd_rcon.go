@@ -0,0 +1,83 @@
+// openttd_rcon runs a script of console commands against a server over the admin port, and prints a transcript.
+package main
+
+import (
+	"flag"
+	"fmt"
+	gopenttd "github.com/ropenttd/gopenttd/pkg/admin"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"strings"
+)
+
+// vars collects -var name=value flags.
+type vars map[string]string
+
+func (v vars) String() string {
+	var pairs []string
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (v vars) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	v[s[:i]] = s[i+1:]
+	return nil
+}
+
+var (
+	serverHost      string
+	serverPort      int
+	serverPass      string
+	scriptPath      string
+	continueOnError bool
+	scriptVars      = vars{}
+)
+
+func init() {
+	flag.StringVar(&serverHost, "target.host", "testserver.ttdredd.it", "Target host to connect to.")
+	flag.IntVar(&serverPort, "target.port", 3977, "Target port (this should be the admin port)")
+	flag.StringVar(&serverPass, "target.pass", "", "Target password")
+	flag.StringVar(&scriptPath, "script", "-", "Script of console commands to run, or - to read it from stdin.")
+	flag.BoolVar(&continueOnError, "continue", false, "Carry on running the script after a command fails.")
+	flag.Var(scriptVars, "var", "Variable to substitute into the script, as name=value. Can be given more than once.")
+	flag.Parse()
+}
+
+func main() {
+	var script io.Reader = os.Stdin
+	if scriptPath != "-" {
+		f, err := os.Open(scriptPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		script = f
+	}
+
+	s, err := gopenttd.New(serverHost, serverPort, serverPass)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// We don't need to keep track of the game, just run commands
+	s.StateEnabled = false
+
+	err = s.Open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	transcript, err := gopenttd.RunScript(s, script, gopenttd.ScriptOptions{Vars: scriptVars, ContinueOnError: continueOnError})
+	fmt.Print(transcript)
+	if err != nil {
+		s.Close()
+		log.Fatal(err)
+	}
+}
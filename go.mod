@@ -3,8 +3,10 @@ module github.com/ropenttd/gopenttd
 go 1.14
 
 require (
+	github.com/chzyer/readline v1.5.1
 	github.com/sirupsen/logrus v1.5.0
 	github.com/skybon/goutil v0.0.0-20170323171401-73acca779463
 	github.com/stretchr/testify v1.2.2
 	golang.org/x/text v0.3.2
+	gopkg.in/yaml.v2 v2.4.0
 )
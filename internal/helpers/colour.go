@@ -1,5 +1,7 @@
 package helpers
 
+import "image/color"
+
 type OpenttdColour uint8
 
 const (
@@ -46,3 +48,31 @@ func (colour OpenttdColour) String() string {
 	}
 	return names[colour]
 }
+
+// companyColourRGB are the main shade of each company colour, approximately as drawn with the default palette.
+var companyColourRGB = [...]color.RGBA{
+	{R: 0x1c, G: 0x3c, B: 0x9c, A: 0xff}, // Dark Blue
+	{R: 0x74, G: 0xac, B: 0x68, A: 0xff}, // Pale Green
+	{R: 0xe0, G: 0x7c, B: 0xa8, A: 0xff}, // Pink
+	{R: 0xf0, G: 0xcc, B: 0x1c, A: 0xff}, // Yellow
+	{R: 0xc4, G: 0x2c, B: 0x2c, A: 0xff}, // Red
+	{R: 0x54, G: 0xa0, B: 0xdc, A: 0xff}, // Light Blue
+	{R: 0x4c, G: 0xa8, B: 0x2c, A: 0xff}, // Green
+	{R: 0x2c, G: 0x6c, B: 0x1c, A: 0xff}, // Dark Green
+	{R: 0x3c, G: 0x64, B: 0xdc, A: 0xff}, // Blue
+	{R: 0xe0, G: 0xc8, B: 0x94, A: 0xff}, // Cream
+	{R: 0xa0, G: 0x7c, B: 0xa8, A: 0xff}, // Mauve
+	{R: 0x7c, G: 0x40, B: 0xa0, A: 0xff}, // Purple
+	{R: 0xf0, G: 0x8c, B: 0x1c, A: 0xff}, // Orange
+	{R: 0x8c, G: 0x5c, B: 0x2c, A: 0xff}, // Brown
+	{R: 0x90, G: 0x90, B: 0x90, A: 0xff}, // Grey
+	{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}, // White
+}
+
+// RGB returns the colour, approximately as drawn in game. Unknown colours are returned as black.
+func (colour OpenttdColour) RGB() color.RGBA {
+	if colour > ColourWhite {
+		return color.RGBA{A: 0xff}
+	}
+	return companyColourRGB[colour]
+}
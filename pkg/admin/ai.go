@@ -0,0 +1,138 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/rcon"
+	"sort"
+	"strings"
+)
+
+// Managing AI companies through the console.
+// The console has no way of changing the settings of an AI that's already running: they can only be given when
+// starting it. Settings that apply to all AIs can be changed with SetAISetting.
+
+// ListAIs returns the AIs the server has available, as shown by "list_ai".
+func (s *Session) ListAIs(ctx context.Context) (ais []rcon.AI, err error) {
+	err = s.consoleQuery(ctx, func(output []string) (err error) {
+		ais, err = rcon.ParseAIList(output)
+		return err
+	}, "list_ai")
+	return ais, err
+}
+
+// aiSettings formats AI settings as start_ai expects them, e.g "min_vehicles=5,use_trains=0".
+func aiSettings(settings map[string]int) string {
+	pairs := make([]string, 0, len(settings))
+	for name, value := range settings {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// SetAISetting changes one of the server's settings for all AIs (and game scripts), i.e those starting with "ai." or
+// "script.", e.g "ai.ai_in_multiplayer", and returns the value it ended up with. See SetSetting.
+// ErrUnknownSetting is returned for other settings.
+func (s *Session) SetAISetting(ctx context.Context, name string, value interface{}) (setting rcon.Setting, err error) {
+	if !strings.HasPrefix(name, "ai.") && !strings.HasPrefix(name, "script.") {
+		return setting, &RconError{Command: consoleLine("setting", name), Err: ErrUnknownSetting}
+	}
+	return s.SetSetting(ctx, name, value)
+}
+
+// StartAI starts the named AI (as shown by ListAIs, optionally followed by ".<version>") in a new company, with the
+// given settings, and returns the ID of its company. If name is empty, the server picks an AI; settings can only be
+// given along with a name, otherwise ErrUnknownAI is returned.
+// The server creates the company in the background, so this waits for its CompanyInfo to arrive, which needs
+// company info updates (as the State asks for). If that doesn't happen before ctx is done, or within RconTimeout if
+// ctx has no deadline, ErrAINotStarted is returned.
+func (s *Session) StartAI(ctx context.Context, name string, settings map[string]int) (id enum.CompanyID, err error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.rconTimeout())
+		defer cancel()
+	}
+
+	args := []string{"start_ai"}
+	if name != "" {
+		args = append(args, name)
+		if len(settings) > 0 {
+			args = append(args, aiSettings(settings))
+		}
+	} else if len(settings) > 0 {
+		return 0, &RconError{Command: consoleLine(args...), Err: ErrUnknownAI}
+	}
+
+	if s.State != nil {
+		if _, _, companies := s.State.Counts(); companies >= enum.MaxCompanies {
+			return 0, &RconError{Command: consoleLine(args...), Err: ErrNoFreeCompanySlot}
+		}
+	}
+
+	// Don't mistake companies that were already there for the new one
+	existing := map[enum.CompanyID]bool{}
+	for _, com := range s.State.AICompanies() {
+		existing[com.ID] = true
+	}
+	started := make(chan enum.CompanyID, 1)
+	remove := s.AddHandler(func(_ *Session, c *CompanyInfo) {
+		if !c.IsAI || existing[c.ID] {
+			return
+		}
+		select {
+		case started <- c.ID:
+		default:
+		}
+	})
+	defer remove()
+
+	if _, err = s.consoleCommandContext(ctx, args...); err != nil {
+		return 0, err
+	}
+	select {
+	case id = <-started:
+		return id, nil
+	case <-ctx.Done():
+		return 0, ErrAINotStarted
+	}
+}
+
+// checkAICompany returns an error if the State doesn't know the company is controlled by an AI, so we don't
+// mistakenly run AI commands on human companies.
+// Without state tracking, this is left to the server, which refuses to run them on human companies itself.
+func (s *Session) checkAICompany(command string, id enum.CompanyID) error {
+	if !s.StateEnabled || s.State == nil {
+		return nil
+	}
+	com, ok := s.State.Company(id)
+	if !ok {
+		return &RconError{Command: command, Err: ErrNoSuchCompany}
+	}
+	if !com.AI {
+		return &RconError{Command: command, Err: ErrHumanCompany}
+	}
+	return nil
+}
+
+// StopAI stops the AI controlling the given company, which removes the company from the game.
+// ErrHumanCompany is returned if the company isn't controlled by an AI, or ErrNoSuchCompany if there's no such
+// company, according to the State (or the server, if StateEnabled isn't set).
+func (s *Session) StopAI(ctx context.Context, id enum.CompanyID) (err error) {
+	if err = s.checkAICompany("stop_ai", id); err != nil {
+		return err
+	}
+	_, err = s.consoleCommandContext(ctx, "stop_ai", consoleCompany(id))
+	return err
+}
+
+// ReloadAI restarts the AI controlling the given company, e.g after upgrading it. The company is kept.
+// Errors are returned as for StopAI.
+func (s *Session) ReloadAI(ctx context.Context, id enum.CompanyID) (err error) {
+	if err = s.checkAICompany("reload_ai", id); err != nil {
+		return err
+	}
+	_, err = s.consoleCommandContext(ctx, "reload_ai", consoleCompany(id))
+	return err
+}
@@ -0,0 +1,103 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAICompanies(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+	s.State.OnInterface(s, &CompanyInfo{ID: 0, Name: "Alice Transport"})
+	s.State.OnInterface(s, &CompanyInfo{ID: 1, Name: "AdmiralAI Transport", IsAI: true})
+
+	// Human companies are never touched
+	err := s.StopAI(context.Background(), 0)
+	assert.True(t, errors.Is(err, ErrHumanCompany))
+	err = s.ReloadAI(context.Background(), 4)
+	assert.True(t, errors.Is(err, ErrNoSuchCompany))
+
+	errs := make(chan error)
+	go func() { errs <- s.ReloadAI(context.Background(), 1) }()
+	waitForRcon(s, "reload_ai 2")
+	s.onInterface(&Rcon{Output: "AI reloaded."})
+	s.onInterface(&RconEnd{Command: "reload_ai 2"})
+	assert.NoError(t, <-errs)
+
+	// Starting an AI waits for its company to turn up
+	type result struct {
+		id  enum.CompanyID
+		err error
+	}
+	results := make(chan result)
+	go func() {
+		id, err := s.StartAI(context.Background(), "CivilAI", map[string]int{"use_trains": 0, "min_towns": 5})
+		results <- result{id, err}
+	}()
+	waitForRcon(s, "start_ai CivilAI min_towns=5,use_trains=0")
+	s.onInterface(&RconEnd{Command: "start_ai CivilAI min_towns=5,use_trains=0"})
+	s.handleEvent(companyInfoEventType, &CompanyInfo{ID: 1, Name: "AdmiralAI Transport", IsAI: true})
+	s.handleEvent(companyInfoEventType, &CompanyInfo{ID: 2, Name: "CivilAI Transport", IsAI: true})
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, enum.CompanyID(2), res.id)
+
+	go func() {
+		_, err := s.StartAI(context.Background(), "NoSuchAI", nil)
+		results <- result{err: err}
+	}()
+	waitForRcon(s, "start_ai NoSuchAI")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourWarning, Output: "Failed to load the specified AI"})
+	s.onInterface(&RconEnd{Command: "start_ai NoSuchAI"})
+	res = <-results
+	assert.True(t, errors.Is(res.err, ErrUnknownAI))
+
+	_, err = s.SetAISetting(context.Background(), "difficulty.max_loan", 10)
+	assert.True(t, errors.Is(err, ErrUnknownSetting))
+}
+
+func TestStartAITimeout(t *testing.T) {
+	s := newTestSession()
+	s.RconTimeout = 50 * time.Millisecond
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	// Even without a deadline, we don't wait forever for the company
+	errs := make(chan error)
+	go func() {
+		_, err := s.StartAI(context.Background(), "", nil)
+		errs <- err
+	}()
+	waitForRcon(s, "start_ai")
+	s.onInterface(&RconEnd{Command: "start_ai"})
+	assert.Equal(t, ErrAINotStarted, <-errs)
+}
+
+func TestAICompaniesWithoutState(t *testing.T) {
+	s := newTestSession()
+	s.StateEnabled = false
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	// The server checks the company itself
+	errs := make(chan error)
+	go func() { errs <- s.StopAI(context.Background(), 0) }()
+	waitForRcon(s, "stop_ai 1")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourWarning, Output: "Company is not controlled by an AI."})
+	s.onInterface(&RconEnd{Command: "stop_ai 1"})
+	assert.True(t, errors.Is(<-errs, ErrHumanCompany))
+
+	go func() { errs <- s.ReloadAI(context.Background(), 4) }()
+	waitForRcon(s, "reload_ai 5")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourDefault, Output: "Unknown company. Company range is between 1 and 15."})
+	s.onInterface(&RconEnd{Command: "reload_ai 5"})
+	assert.True(t, errors.Is(<-errs, ErrNoSuchCompany))
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/packets"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Chat related stuff is dealt with in this file to help keep things a little tidier.
+
+// NetworkChatLength is the maximum length of a chat message in bytes, as defined by OpenTTD (including the terminator).
+const NetworkChatLength = 900
+
+// Broadcast sends a chat message to everybody on the server.
+func (s *Session) Broadcast(message string) (err error) {
+	return s.Chat(enum.ActionChat, enum.DestinationBroadcast, 0, message)
+}
+
+// SendToClient sends a private chat message to the given client.
+func (s *Session) SendToClient(id enum.ClientID, message string) (err error) {
+	return s.Chat(enum.ActionChatClient, enum.DestinationClient, uint32(id), message)
+}
+
+// SendToCompany sends a chat message to all clients playing in the given company.
+func (s *Session) SendToCompany(id enum.CompanyID, message string) (err error) {
+	return s.Chat(enum.ActionChatCompany, enum.DestinationTeam, uint32(id), message)
+}
+
+// Chat sends a chat message (who'dve thought it?)
+// You probably want Broadcast, SendToClient or SendToCompany instead, which pick the right Action and Destination for you.
+// Messages longer than OpenTTD allows are split into several, and all messages are subject to the
+// Session's ChatRateLimit. ErrDisconnected is returned if we aren't connected (anymore) when a part is due to be sent.
+func (s *Session) Chat(act enum.Action, dest enum.Destination, destID uint32, message string) (err error) {
+	// Hold the chat lock for all parts, so split messages aren't interleaved with others
+	s.chatMutex.Lock()
+	defer s.chatMutex.Unlock()
+
+	for _, part := range splitChatMessage(message, NetworkChatLength-1) {
+		s.chatLimiter.wait(s.ChatRateLimit, s.ChatBurst)
+
+		data := packets.AdminChat{
+			Action:        act,
+			Destination:   dest,
+			DestinationID: destID,
+			Message:       part,
+		}
+		// We may well have been disconnected while waiting
+		s.connMutex.Lock()
+		if s.conn == nil {
+			err = ErrDisconnected
+		} else {
+			err = writePacketToTcpConn(s.conn, data)
+		}
+		s.connMutex.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitChatMessage splits a message into parts no longer than limit bytes.
+// Splits happen at the last space in each part where possible, and never in the middle of a UTF-8 character.
+func splitChatMessage(message string, limit int) (parts []string) {
+	for len(message) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// Not valid UTF-8, so there's nothing better we can do
+			cut = limit
+		}
+		// Prefer splitting between words, as long as that doesn't make for a tiny part
+		// (a space straight after the cut is fine too, as it gets dropped)
+		if i := strings.LastIndexByte(message[:cut+1], ' '); i >= limit/2 {
+			parts = append(parts, message[:i])
+			message = message[i+1:]
+			continue
+		}
+		parts = append(parts, message[:cut])
+		message = message[cut:]
+	}
+	return append(parts, message)
+}
+
+// chatLimiter is a simple token bucket, used to stop us flooding players with chat messages.
+type chatLimiter struct {
+	sync.Mutex
+	tokens int
+	last   time.Time
+
+	// The clock, which tests can replace. Defaults to time.Now and time.Sleep.
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// wait blocks until a message may be sent, allowing burst messages at once and one per interval thereafter.
+// An interval of zero disables rate limiting.
+func (l *chatLimiter) wait(interval time.Duration, burst int) {
+	if interval <= 0 {
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	if l.now == nil {
+		l.now, l.sleep = time.Now, time.Sleep
+	}
+
+	now := l.now()
+	if l.last.IsZero() {
+		l.tokens = burst
+		l.last = now
+	}
+
+	// Refill the bucket for the time that has passed
+	if refill := int(now.Sub(l.last) / interval); refill > 0 {
+		l.tokens += refill
+		l.last = l.last.Add(time.Duration(refill) * interval)
+		if l.tokens >= burst {
+			l.tokens = burst
+			l.last = now
+		}
+	}
+
+	if l.tokens > 0 {
+		l.tokens--
+		return
+	}
+
+	// Out of tokens: wait for the next one, and use it straight away
+	l.sleep(l.last.Add(interval).Sub(now))
+	l.last = l.last.Add(interval)
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestSplitChatMessage(t *testing.T) {
+	assert.Equal(t, []string{"hello"}, splitChatMessage("hello", 10))
+	assert.Equal(t, []string{"hello", "world"}, splitChatMessage("hello world", 10))
+
+	// No spaces to split on, so we split at the limit
+	assert.Equal(t, []string{"abcdefghij", "klm"}, splitChatMessage("abcdefghijklm", 10))
+
+	// Multi-byte characters must never be cut in half
+	msg := strings.Repeat("é", 10) // 2 bytes each
+	parts := splitChatMessage(msg, 5)
+	assert.Equal(t, msg, strings.Join(parts, ""))
+	for _, p := range parts {
+		assert.True(t, utf8.ValidString(p), "part %q is not valid UTF-8", p)
+		assert.True(t, len(p) <= 5)
+	}
+}
+
+func TestChatLimiter(t *testing.T) {
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept time.Duration
+	l := chatLimiter{
+		now: func() time.Time { return clock },
+		sleep: func(d time.Duration) {
+			slept += d
+			clock = clock.Add(d)
+		},
+	}
+
+	// A burst goes straight through
+	for i := 0; i < 3; i++ {
+		l.wait(time.Second, 3)
+	}
+	assert.Zero(t, slept)
+
+	// Then we're throttled to one per interval
+	l.wait(time.Second, 3)
+	assert.Equal(t, time.Second, slept)
+	clock = clock.Add(400 * time.Millisecond)
+	l.wait(time.Second, 3)
+	assert.Equal(t, 1600*time.Millisecond, slept)
+
+	// Waiting refills the bucket, but never beyond the burst
+	clock = clock.Add(time.Hour)
+	slept = 0
+	for i := 0; i < 3; i++ {
+		l.wait(time.Second, 3)
+	}
+	assert.Zero(t, slept)
+	l.wait(time.Second, 3)
+	assert.Equal(t, time.Second, slept)
+
+	// No interval means no limit
+	slept = 0
+	for i := 0; i < 10; i++ {
+		l.wait(0, 3)
+	}
+	assert.Zero(t, slept)
+}
+
+func TestChatDisconnected(t *testing.T) {
+	s := newTestSession()
+	sent := newCapturingConnection(t, s)
+	s.listening = make(chan interface{})
+	s.ChatRateLimit = time.Second
+	s.ChatBurst = 1
+
+	// The session closes while the second part waits for the limiter
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.chatLimiter.now = func() time.Time { return clock }
+	s.chatLimiter.sleep = func(d time.Duration) {
+		s.Close()
+		clock = clock.Add(d)
+	}
+
+	err := s.Broadcast(strings.Repeat("a", NetworkChatLength+10))
+	assert.Equal(t, ErrDisconnected, err)
+	assert.NotNil(t, <-sent)
+}
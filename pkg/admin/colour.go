@@ -0,0 +1,26 @@
+package admin
+
+import "github.com/ropenttd/gopenttd/pkg/admin/enum"
+
+// Rendering console output in the colours it's shown in game.
+
+// ANSI returns the output with the escape codes to show it in its colour on a terminal.
+func (r Rcon) ANSI() string {
+	return r.Colour.ANSI(r.Output)
+}
+
+// HTML returns the output, escaped, in a span showing it in its colour.
+func (r Rcon) HTML() string {
+	return r.Colour.HTML(r.Output)
+}
+
+// ANSI returns the message with the escape codes to show it on a terminal as the server's console would.
+// Console messages don't say what colour they are, so they're shown in the default console colour.
+func (c Console) ANSI() string {
+	return enum.ConsoleColourDefault.ANSI(c.Message)
+}
+
+// HTML returns the message, escaped, in a span showing it as the server's console would.
+func (c Console) HTML() string {
+	return enum.ConsoleColourDefault.HTML(c.Message)
+}
@@ -0,0 +1,66 @@
+package enum
+
+import (
+	"fmt"
+	"html"
+	"image/color"
+)
+
+// Rendering text in the colours OpenTTD draws it in, e.g to show console output as it looks in game.
+
+// textColourRGB are the colours of each TextColour, approximately as drawn with the default palette.
+var textColourRGB = [...]color.RGBA{
+	{R: 0x5c, G: 0x84, B: 0xe0, A: 0xff}, // Blue
+	{R: 0xb0, G: 0xb0, B: 0xb0, A: 0xff}, // Silver
+	{R: 0xfc, G: 0xc4, B: 0x28, A: 0xff}, // Gold
+	{R: 0xf0, G: 0x38, B: 0x38, A: 0xff}, // Red
+	{R: 0xac, G: 0x80, B: 0xd4, A: 0xff}, // Purple
+	{R: 0xc8, G: 0x98, B: 0x64, A: 0xff}, // LightBrown
+	{R: 0xfc, G: 0x88, B: 0x10, A: 0xff}, // Orange
+	{R: 0x68, G: 0xd4, B: 0x20, A: 0xff}, // Green
+	{R: 0xfc, G: 0xfc, B: 0x00, A: 0xff}, // Yellow
+	{R: 0x30, G: 0x88, B: 0x14, A: 0xff}, // DarkGreen
+	{R: 0xf4, G: 0xe4, B: 0xb4, A: 0xff}, // Cream
+	{R: 0x98, G: 0x6c, B: 0x38, A: 0xff}, // Brown
+	{R: 0xfc, G: 0xfc, B: 0xfc, A: 0xff}, // White
+	{R: 0x94, G: 0xbc, B: 0xfc, A: 0xff}, // LightBlue
+	{R: 0x84, G: 0x84, B: 0x84, A: 0xff}, // Grey
+	{R: 0x30, G: 0x50, B: 0xa4, A: 0xff}, // DarkBlue
+	{R: 0x00, G: 0x00, B: 0x00, A: 0xff}, // Black
+}
+
+// Base returns the colour without any of the TextColour flags set.
+func (c TextColour) Base() TextColour {
+	return c &^ (TextColourNoShade | TextColourForced)
+}
+
+// IsValid returns whether the colour is one of the known TextColours (ignoring flags), rather than a palette
+// colour or one from a newer OpenTTD version.
+func (c TextColour) IsValid() bool {
+	return c.Base() <= TextColourBlack
+}
+
+// RGB returns the colour as drawn in game. Colours that aren't valid are drawn as ConsoleColourDefault.
+func (c TextColour) RGB() color.RGBA {
+	if !c.IsValid() {
+		return textColourRGB[ConsoleColourDefault]
+	}
+	return textColourRGB[c.Base()]
+}
+
+// Hex returns the colour as drawn in game as a CSS-style hex colour, e.g "#f03838".
+func (c TextColour) Hex() string {
+	rgb := c.RGB()
+	return fmt.Sprintf("#%02x%02x%02x", rgb.R, rgb.G, rgb.B)
+}
+
+// ANSI returns the text wrapped in the escape codes to show it in this colour on a (24-bit colour) terminal.
+func (c TextColour) ANSI(text string) string {
+	rgb := c.RGB()
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm%s\x1b[0m", rgb.R, rgb.G, rgb.B, text)
+}
+
+// HTML returns the text, escaped, in a span showing it in this colour.
+func (c TextColour) HTML(text string) string {
+	return fmt.Sprintf(`<span style="color: %s">%s</span>`, c.Hex(), html.EscapeString(text))
+}
@@ -0,0 +1,33 @@
+package enum
+
+import (
+	"github.com/stretchr/testify/assert"
+	"image/color"
+	"testing"
+)
+
+func TestTextColour(t *testing.T) {
+	// Values as defined in gfx_type.h
+	assert.EqualValues(t, 0x03, TextColourRed)
+	assert.EqualValues(t, 0x10, TextColourBlack)
+	assert.Equal(t, TextColourRed, ConsoleColourError)
+	assert.Equal(t, "LightBlue", ConsoleColourWarning.String())
+	assert.Equal(t, ConsoleColourWarning, ConsoleColourHelp)
+
+	// Flags don't change the colour, but palette colours aren't text colours
+	assert.Equal(t, TextColourRed, (TextColourRed | TextColourNoShade | TextColourForced).Base())
+	assert.Equal(t, "Red", (TextColourRed | TextColourForced).String())
+	text, err := (TextColourRed | TextColourNoShade).MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "Red", string(text))
+	text, err = (TextColourIsPaletteColour | 5).MarshalText()
+	assert.NoError(t, err)
+	assert.Equal(t, "261", string(text))
+	assert.Equal(t, "#f03838", (TextColourRed | TextColourForced).Hex())
+	assert.False(t, (TextColourIsPaletteColour | 5).IsValid())
+	assert.Equal(t, ConsoleColourDefault.RGB(), (TextColourIsPaletteColour | 5).RGB())
+	assert.Equal(t, color.RGBA{A: 0xff}, TextColourBlack.RGB())
+
+	assert.Equal(t, "\x1b[38;2;240;56;56mOops\x1b[0m", TextColourRed.ANSI("Oops"))
+	assert.Equal(t, `<span style="color: #f03838">&lt;Oops&gt;</span>`, TextColourRed.HTML("<Oops>"))
+}
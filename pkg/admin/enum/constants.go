@@ -37,10 +37,12 @@ const (
 	CompanyRemoveReasonBankrupt
 )
 
+// As defined in https://github.com/OpenTTD/OpenTTD/blob/master/src/network/network_type.h
+
 type Action uint8
 
 const (
-	ActionJoin Action = 0x00
+	ActionJoin Action = iota
 	ActionLeave
 	ActionServerMessage
 	ActionChat
@@ -51,20 +53,22 @@ const (
 	ActionCompanySpectator
 	ActionCompanyJoin
 	ActionCompanyNew // 0x0A
+	ActionKicked
+	ActionExternalChat
 )
 
 type Destination uint8
 
 const (
-	DestinationBroadcast Destination = 0x00 << iota // All destinations
-	DestinationTeam                                 // A specific team
-	DestinationClient                               // A specific client
+	DestinationBroadcast Destination = iota // All destinations
+	DestinationTeam                         // A specific team
+	DestinationClient                       // A specific client
 )
 
 type NetError uint8
 
 const (
-	NetErrorGeneral NetError = 0x00 << iota // A general network failure
+	NetErrorGeneral NetError = iota // A general network failure
 
 	// Signals from clients
 	NetErrorDesync
@@ -84,15 +88,55 @@ const (
 	NetErrorCheater
 	NetErrorFull
 	NetErrorTooManyCommands // 0x0F
+	NetErrorTimeoutPassword
+	NetErrorTimeoutComputer
+	NetErrorTimeoutMap
+	NetErrorTimeoutJoin
+	NetErrorInvalidClientName
+	NetErrorNotOnAllowList
+	NetErrorNoAuthenticationMethodAvailable
+)
+
+// As defined in https://github.com/OpenTTD/OpenTTD/blob/master/src/gfx_type.h and console_type.h
+
+// TextColour is the colour text is drawn in, e.g the colour of each line of RCON output.
+type TextColour uint16
+
+const (
+	TextColourBlue TextColour = iota
+	TextColourSilver
+	TextColourGold
+	TextColourRed
+	TextColourPurple
+	TextColourLightBrown
+	TextColourOrange
+	TextColourGreen
+	TextColourYellow
+	TextColourDarkGreen
+	TextColourCream
+	TextColourBrown
+	TextColourWhite
+	TextColourLightBlue
+	TextColourGrey
+	TextColourDarkBlue
+	TextColourBlack
 )
 
-type ClientID uint32
+// Flags which can be set on a TextColour.
+const (
+	TextColourIsPaletteColour TextColour = 0x100 // The colour is an index into the palette, rather than a TextColour
+	TextColourNoShade         TextColour = 0x200 // Don't draw a shadow behind the text
+	TextColourForced          TextColour = 0x400 // Ignore colour changes within the text
+)
 
+// The colours console output is printed in.
 const (
-	// Client is not part of anything
-	ClientIDInvalid = 0x00 << iota
-	// Server is guaranteed to have this Client ID
-	ClientIDServer = 0x01
-	// The first Client ID
-	ClientIDFirst = 0x02
+	ConsoleColourDefault = TextColourSilver
+	ConsoleColourError   = TextColourRed
+	ConsoleColourWarning = TextColourLightBlue
+	ConsoleColourHelp    = TextColourLightBlue // The same as warnings, so they can't be told apart
+	ConsoleColourInfo    = TextColourYellow
+	ConsoleColourDebug   = TextColourLightBrown
+	ConsoleColourCommand = TextColourGold
+	ConsoleColourWhite   = TextColourWhite
 )
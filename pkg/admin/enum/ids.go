@@ -0,0 +1,160 @@
+package enum
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// As defined in https://github.com/OpenTTD/OpenTTD/blob/master/src/network/network_type.h
+// and https://github.com/OpenTTD/OpenTTD/blob/master/src/company_type.h
+
+// ErrInvalidID is returned when text can't be parsed as a ClientID or CompanyID.
+var ErrInvalidID = errors.New("invalid ID")
+
+// ClientID is the unique ID of a client connected to the server.
+type ClientID uint32
+
+const (
+	// Client is not part of anything
+	ClientIDInvalid ClientID = 0x00
+	// Server is guaranteed to have this Client ID
+	ClientIDServer ClientID = 0x01
+	// The first Client ID
+	ClientIDFirst ClientID = 0x02
+)
+
+// IsServer returns whether the client is the server itself (i.e the host on a listen server).
+func (id ClientID) IsServer() bool {
+	return id == ClientIDServer
+}
+
+// IsValid returns whether the ID refers to an actual client.
+func (id ClientID) IsValid() bool {
+	return id != ClientIDInvalid
+}
+
+// String returns a human readable representation of the client, e.g "Client #5".
+func (id ClientID) String() string {
+	switch id {
+	case ClientIDInvalid:
+		return "Invalid Client"
+	case ClientIDServer:
+		return "Server"
+	}
+	return fmt.Sprintf("Client #%d", uint32(id))
+}
+
+// MarshalText encodes the client ID as a decimal number.
+func (id ClientID) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(id), 10)), nil
+}
+
+// UnmarshalText decodes a decimal client ID. "server" is also accepted.
+func (id *ClientID) UnmarshalText(text []byte) error {
+	if strings.EqualFold(string(text), "server") {
+		*id = ClientIDServer
+		return nil
+	}
+	v, err := strconv.ParseUint(string(text), 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidID, text)
+	}
+	*id = ClientID(v)
+	return nil
+}
+
+// MarshalJSON encodes the client ID as a JSON number.
+func (id ClientID) MarshalJSON() ([]byte, error) {
+	return id.MarshalText()
+}
+
+// UnmarshalJSON decodes a client ID from a JSON number or string.
+func (id *ClientID) UnmarshalJSON(data []byte) error {
+	return id.UnmarshalText([]byte(strings.Trim(string(data), `"`)))
+}
+
+// CompanyID is the ID of a company, as used by the admin protocol.
+// Company IDs are 0-based, whereas players (and the server console) see them 1-based: use Number() for those.
+type CompanyID uint8
+
+const (
+	// The first Company ID
+	CompanyIDFirst CompanyID = 0x00
+	// The maximum number of companies in a game
+	MaxCompanies = 0x0F
+	// A client that is not in a company yet
+	CompanyIDInactiveClient CompanyID = 0xFD
+	// A client that is about to found a new company
+	CompanyIDNewCompany CompanyID = 0xFE
+	// A client that is spectating
+	CompanyIDSpectator CompanyID = 0xFF
+)
+
+// CompanyIDFromNumber converts a 1-based company number, as seen by players and used by console commands,
+// into a CompanyID. The second return value is false if the number isn't a valid company number.
+func CompanyIDFromNumber(n uint8) (CompanyID, bool) {
+	if n == 0 || n > MaxCompanies {
+		return CompanyIDSpectator, false
+	}
+	return CompanyID(n - 1), true
+}
+
+// IsSpectator returns whether the ID means a client is spectating, rather than playing in a company.
+func (id CompanyID) IsSpectator() bool {
+	return id == CompanyIDSpectator
+}
+
+// IsValid returns whether the ID refers to an actual company slot.
+func (id CompanyID) IsValid() bool {
+	return id < MaxCompanies
+}
+
+// Number returns the 1-based company number that players see (e.g ID 2 is shown in-game as "Company #3").
+func (id CompanyID) Number() uint8 {
+	return uint8(id) + 1
+}
+
+// String returns a human readable representation of the company, e.g "Company #3".
+func (id CompanyID) String() string {
+	switch id {
+	case CompanyIDSpectator:
+		return "Spectator"
+	case CompanyIDNewCompany:
+		return "New Company"
+	case CompanyIDInactiveClient:
+		return "Inactive Client"
+	}
+	return fmt.Sprintf("Company #%d", id.Number())
+}
+
+// MarshalText encodes the company ID as a 0-based decimal number (spectators are 255), the same as MarshalJSON,
+// so IDs look the same whether they're map keys or values.
+func (id CompanyID) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatUint(uint64(id), 10)), nil
+}
+
+// UnmarshalText decodes a 0-based decimal company ID, or "spectator".
+func (id *CompanyID) UnmarshalText(text []byte) error {
+	if strings.EqualFold(string(text), "spectator") {
+		*id = CompanyIDSpectator
+		return nil
+	}
+	v, err := strconv.ParseUint(string(text), 10, 8)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidID, text)
+	}
+	*id = CompanyID(v)
+	return nil
+}
+
+// MarshalJSON encodes the company ID as a 0-based JSON number (spectators are 255).
+func (id CompanyID) MarshalJSON() ([]byte, error) {
+	return id.MarshalText()
+}
+
+// UnmarshalJSON decodes a company ID from a JSON number or string.
+func (id *CompanyID) UnmarshalJSON(data []byte) error {
+	return id.UnmarshalText([]byte(strings.Trim(string(data), `"`)))
+}
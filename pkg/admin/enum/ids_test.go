@@ -0,0 +1,38 @@
+package enum
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCompanyIDFromNumber(t *testing.T) {
+	id, ok := CompanyIDFromNumber(3)
+	assert.True(t, ok)
+	assert.Equal(t, CompanyID(2), id)
+	assert.Equal(t, uint8(3), id.Number())
+
+	_, ok = CompanyIDFromNumber(MaxCompanies)
+	assert.True(t, ok)
+	for _, n := range []uint8{0, MaxCompanies + 1, 255} {
+		id, ok = CompanyIDFromNumber(n)
+		assert.False(t, ok, n)
+		assert.False(t, id.IsValid(), n)
+	}
+}
+
+func TestCompanyIDMarshalling(t *testing.T) {
+	// Keys and values look the same
+	b, err := json.Marshal(map[CompanyID]CompanyID{CompanyIDSpectator: CompanyIDSpectator, 2: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"2":2,"255":255}`, string(b))
+
+	var out map[CompanyID]CompanyID
+	assert.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, CompanyID(2), out[2])
+	assert.Equal(t, CompanyIDSpectator, out[CompanyIDSpectator])
+
+	var id CompanyID
+	assert.NoError(t, id.UnmarshalText([]byte("spectator")))
+	assert.True(t, id.IsSpectator())
+}
@@ -0,0 +1,314 @@
+package enum
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// String representations of the enums in this package.
+// Names are accepted case-insensitively when unmarshalling, and underscores, dashes and spaces are ignored,
+// so "client_info", "CLIENT-INFO" and "ClientInfo" are all the same UpdateType.
+
+// ErrUnknownValue is returned when text can't be unmarshalled into an enum value.
+var ErrUnknownValue = errors.New("unknown enum value")
+
+var updateTypeNames = [...]string{
+	"Date",
+	"ClientInfo",
+	"CompanyInfo",
+	"CompanyEconomy",
+	"CompanyStats",
+	"Chat",
+	"Console",
+	"CmdNames",
+	"CmdLogging",
+	"Gamescript",
+}
+
+var updateFrequencyNames = [...]string{
+	"Poll",
+	"Daily",
+	"Weekly",
+	"Monthly",
+	"Quarterly",
+	"Annually",
+	"Automatically",
+}
+
+var companyRemoveReasonNames = [...]string{
+	"Manual",
+	"Autoclean",
+	"Bankrupt",
+}
+
+var actionNames = [...]string{
+	"Join",
+	"Leave",
+	"ServerMessage",
+	"Chat",
+	"ChatCompany",
+	"ChatClient",
+	"GiveMoney",
+	"NameChange",
+	"CompanySpectator",
+	"CompanyJoin",
+	"CompanyNew",
+	"Kicked",
+	"ExternalChat",
+}
+
+var destinationNames = [...]string{
+	"Broadcast",
+	"Team",
+	"Client",
+}
+
+var netErrorNames = [...]string{
+	"General",
+	"Desync",
+	"SavegameFailed",
+	"ConnectionLost",
+	"IllegalPacket",
+	"NewgrfMismatch",
+	"NotAuthorized",
+	"NotExpected",
+	"WrongRevision",
+	"NameInUse",
+	"WrongPassword",
+	"CompanyMismatch",
+	"Kicked",
+	"Cheater",
+	"Full",
+	"TooManyCommands",
+	"TimeoutPassword",
+	"TimeoutComputer",
+	"TimeoutMap",
+	"TimeoutJoin",
+	"InvalidClientName",
+	"NotOnAllowList",
+	"NoAuthenticationMethodAvailable",
+}
+
+var textColourNames = [...]string{
+	"Blue",
+	"Silver",
+	"Gold",
+	"Red",
+	"Purple",
+	"LightBrown",
+	"Orange",
+	"Green",
+	"Yellow",
+	"DarkGreen",
+	"Cream",
+	"Brown",
+	"White",
+	"LightBlue",
+	"Grey",
+	"DarkBlue",
+	"Black",
+}
+
+// nameOf returns the name of the given value, or "Unknown" if it's out of range.
+func nameOf(names []string, v int) string {
+	// prevent panics for out of range lookups
+	if v < 0 || v >= len(names) {
+		return "Unknown"
+	}
+	return names[v]
+}
+
+// marshalName returns the name of the given value, falling back to the number itself if it's out of range
+// (so that values from newer OpenTTD versions survive a round trip).
+func marshalName(names []string, v int) []byte {
+	if v < 0 || v >= len(names) {
+		return []byte(strconv.Itoa(v))
+	}
+	return []byte(names[v])
+}
+
+// normaliseName lowercases the name and strips any word separators from it.
+func normaliseName(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(name))
+}
+
+// unmarshalName finds the value of the given name, which may also be a plain number.
+func unmarshalName(names []string, text []byte, bitSize int) (int, error) {
+	name := normaliseName(string(text))
+	for i, n := range names {
+		if normaliseName(n) == name {
+			return i, nil
+		}
+	}
+	if v, err := strconv.ParseUint(string(text), 10, bitSize); err == nil {
+		return int(v), nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnknownValue, text)
+}
+
+// String returns the name of the UpdateType.
+func (t UpdateType) String() string {
+	return nameOf(updateTypeNames[:], int(t))
+}
+
+// MarshalText encodes the UpdateType as its name.
+func (t UpdateType) MarshalText() ([]byte, error) {
+	return marshalName(updateTypeNames[:], int(t)), nil
+}
+
+// UnmarshalText decodes an UpdateType from its name.
+func (t *UpdateType) UnmarshalText(text []byte) error {
+	v, err := unmarshalName(updateTypeNames[:], text, 8)
+	if err != nil {
+		return err
+	}
+	*t = UpdateType(v)
+	return nil
+}
+
+// String returns the name of the UpdateFrequency.
+// As frequencies are a bitmask (e.g in the Protocol packet), multiple frequencies are separated by a "|".
+func (f UpdateFrequency) String() string {
+	if f == 0 {
+		return "None"
+	}
+	var names []string
+	for i, n := range updateFrequencyNames {
+		if f&(1<<uint(i)) != 0 {
+			names = append(names, n)
+		}
+	}
+	if f>>uint(len(updateFrequencyNames)) != 0 {
+		names = append(names, "Unknown")
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalText encodes the UpdateFrequency as its name(s), separated by "|".
+func (f UpdateFrequency) MarshalText() ([]byte, error) {
+	if f == 0 || f>>uint(len(updateFrequencyNames)) != 0 {
+		return []byte(strconv.FormatUint(uint64(f), 10)), nil
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText decodes an UpdateFrequency from its name(s), separated by "|".
+func (f *UpdateFrequency) UnmarshalText(text []byte) error {
+	if v, err := strconv.ParseUint(string(text), 10, 16); err == nil {
+		*f = UpdateFrequency(v)
+		return nil
+	}
+	var res UpdateFrequency
+	for _, part := range strings.Split(string(text), "|") {
+		bit, err := unmarshalName(updateFrequencyNames[:], []byte(strings.TrimSpace(part)), 4)
+		if err != nil {
+			return err
+		}
+		res |= 1 << uint(bit)
+	}
+	*f = res
+	return nil
+}
+
+// String returns the name of the CompanyRemoveReason.
+func (r CompanyRemoveReason) String() string {
+	return nameOf(companyRemoveReasonNames[:], int(r))
+}
+
+// MarshalText encodes the CompanyRemoveReason as its name.
+func (r CompanyRemoveReason) MarshalText() ([]byte, error) {
+	return marshalName(companyRemoveReasonNames[:], int(r)), nil
+}
+
+// UnmarshalText decodes a CompanyRemoveReason from its name.
+func (r *CompanyRemoveReason) UnmarshalText(text []byte) error {
+	v, err := unmarshalName(companyRemoveReasonNames[:], text, 8)
+	if err != nil {
+		return err
+	}
+	*r = CompanyRemoveReason(v)
+	return nil
+}
+
+// String returns the name of the Action.
+func (a Action) String() string {
+	return nameOf(actionNames[:], int(a))
+}
+
+// MarshalText encodes the Action as its name.
+func (a Action) MarshalText() ([]byte, error) {
+	return marshalName(actionNames[:], int(a)), nil
+}
+
+// UnmarshalText decodes an Action from its name.
+func (a *Action) UnmarshalText(text []byte) error {
+	v, err := unmarshalName(actionNames[:], text, 8)
+	if err != nil {
+		return err
+	}
+	*a = Action(v)
+	return nil
+}
+
+// String returns the name of the Destination.
+func (d Destination) String() string {
+	return nameOf(destinationNames[:], int(d))
+}
+
+// MarshalText encodes the Destination as its name.
+func (d Destination) MarshalText() ([]byte, error) {
+	return marshalName(destinationNames[:], int(d)), nil
+}
+
+// UnmarshalText decodes a Destination from its name.
+func (d *Destination) UnmarshalText(text []byte) error {
+	v, err := unmarshalName(destinationNames[:], text, 8)
+	if err != nil {
+		return err
+	}
+	*d = Destination(v)
+	return nil
+}
+
+// String returns the name of the NetError.
+func (e NetError) String() string {
+	return nameOf(netErrorNames[:], int(e))
+}
+
+// MarshalText encodes the NetError as its name.
+func (e NetError) MarshalText() ([]byte, error) {
+	return marshalName(netErrorNames[:], int(e)), nil
+}
+
+// UnmarshalText decodes a NetError from its name.
+func (e *NetError) UnmarshalText(text []byte) error {
+	v, err := unmarshalName(netErrorNames[:], text, 8)
+	if err != nil {
+		return err
+	}
+	*e = NetError(v)
+	return nil
+}
+
+// String returns the name of the TextColour.
+func (c TextColour) String() string {
+	return nameOf(textColourNames[:], int(c.Base()))
+}
+
+// MarshalText encodes the TextColour as its name, without any flags.
+// Palette colours are encoded as a number.
+func (c TextColour) MarshalText() ([]byte, error) {
+	return marshalName(textColourNames[:], int(c.Base())), nil
+}
+
+// UnmarshalText decodes a TextColour from its name.
+func (c *TextColour) UnmarshalText(text []byte) error {
+	v, err := unmarshalName(textColourNames[:], text, 16)
+	if err != nil {
+		return err
+	}
+	*c = TextColour(v)
+	return nil
+}
@@ -0,0 +1,54 @@
+package enum
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnumValues(t *testing.T) {
+	// Values as defined in network_type.h
+	assert.EqualValues(t, 0x01, ActionLeave)
+	assert.EqualValues(t, 0x0A, ActionCompanyNew)
+	assert.EqualValues(t, 0x0C, ActionExternalChat)
+	assert.EqualValues(t, 0x01, DestinationTeam)
+	assert.EqualValues(t, 0x02, DestinationClient)
+	assert.EqualValues(t, 0x05, NetErrorNewgrfMismatch)
+	assert.EqualValues(t, 0x0F, NetErrorTooManyCommands)
+}
+
+func TestEnumStrings(t *testing.T) {
+	assert.Equal(t, "CmdLogging", UpdateTypeCmdLogging.String())
+	assert.Equal(t, "ChatClient", ActionChatClient.String())
+	assert.Equal(t, "Team", DestinationTeam.String())
+	assert.Equal(t, "WrongPassword", NetErrorWrongPassword.String())
+	assert.Equal(t, "Bankrupt", CompanyRemoveReasonBankrupt.String())
+	assert.Equal(t, "Poll|Daily|Automatically", (UpdateFrequencyPoll | UpdateFrequencyDaily | UpdateFrequencyAutomatically).String())
+	assert.Equal(t, "Unknown", Action(200).String())
+}
+
+func TestEnumText(t *testing.T) {
+	var ut UpdateType
+	assert.NoError(t, ut.UnmarshalText([]byte("company_economy")))
+	assert.Equal(t, UpdateTypeCompanyEconomy, ut)
+
+	var f UpdateFrequency
+	assert.NoError(t, f.UnmarshalText([]byte("monthly | QUARTERLY")))
+	assert.Equal(t, UpdateFrequencyMonthly|UpdateFrequencyQuarterly, f)
+
+	var a Action
+	assert.Error(t, a.UnmarshalText([]byte("dance")))
+
+	// Values unknown to us must survive a round trip.
+	b, err := json.Marshal(map[string]interface{}{"action": Action(200), "dest": DestinationClient})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"action":"200","dest":"Client"}`, string(b))
+
+	var out struct {
+		Action Action      `json:"action"`
+		Dest   Destination `json:"dest"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &out))
+	assert.Equal(t, Action(200), out.Action)
+	assert.Equal(t, DestinationClient, out.Dest)
+}
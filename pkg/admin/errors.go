@@ -13,6 +13,52 @@ var ErrNilState = errors.New("state not instantiated, please use admin.New() or
 // requested is not found
 var ErrStateNotFound = errors.New("state cache not found")
 
+// ErrStateVersion is returned when a stored state was written in a format we don't understand.
+var ErrStateVersion = errors.New("unsupported stored state version")
+
+// ErrDisconnected is returned when we were disconnected from the server while waiting for it.
+var ErrDisconnected = errors.New("disconnected from server")
+
+// ErrRconTimeout is returned when the server didn't finish an RCON command in time.
+var ErrRconTimeout = errors.New("timed out waiting for rcon command to finish")
+
 var ErrServerFull = errors.New("server is full")
 var ErrServerBanned = errors.New("banned from server")
 var ErrServerError = errors.New("server encountered an error")
+
+// Errors returned (wrapped in an RconError) by console commands such as Kick, when the server says they failed.
+var ErrNoSuchClient = errors.New("no such client")
+var ErrNoSuchCompany = errors.New("no such company")
+var ErrCompanyInUse = errors.New("company has clients connected to it")
+var ErrAICompany = errors.New("company is controlled by an AI")
+var ErrNotAllowed = errors.New("not allowed to do that to the server")
+var ErrAlreadyPaused = errors.New("game is already paused")
+var ErrNotPaused = errors.New("game is not paused")
+var ErrNotBanned = errors.New("address is not banned")
+var ErrUnknownCommand = errors.New("unknown console command")
+var ErrCommandFailed = errors.New("console command failed")
+var ErrUnknownSetting = errors.New("unknown setting")
+var ErrInvalidSettingValue = errors.New("invalid value for setting")
+var ErrNetworkSetting = errors.New("setting can't be changed in a network game")
+
+// ErrSettingNotApplied is returned when a setting was changed but didn't end up with the wanted value,
+// e.g because the value was out of range and the server clamped it.
+var ErrSettingNotApplied = errors.New("setting did not take the wanted value")
+
+// ErrUndefinedVariable is returned by RunScript when the script uses a variable it wasn't given.
+var ErrUndefinedVariable = errors.New("undefined variable")
+
+// ErrNoSuchSave is returned (wrapped in an RconError) when deleting a savegame that doesn't exist.
+var ErrNoSuchSave = errors.New("no such savegame")
+
+// ErrSaveUnconfirmed is returned when the server didn't say whether saving the game worked before we gave up
+// waiting.
+var ErrSaveUnconfirmed = errors.New("save was not confirmed")
+
+// Errors returned (wrapped in an RconError) by the AI methods, such as StartAI.
+var ErrHumanCompany = errors.New("company is not controlled by an AI")
+var ErrNoFreeCompanySlot = errors.New("no free company slots")
+var ErrUnknownAI = errors.New("no such AI")
+
+// ErrAINotStarted is returned by StartAI when the new AI company didn't appear before we gave up waiting.
+var ErrAINotStarted = errors.New("AI company did not start")
@@ -220,6 +220,14 @@ func (s *Session) onInterface(i interface{}) {
 	if err != nil {
 		s.log(LogDebug, "error dispatching internal event, %s", err)
 	}
+
+	// Keep the store's history up to date
+	switch t := i.(type) {
+	case *CompanyEconomy:
+		s.queueHistory(t.ID)
+	case *CompanyStats:
+		s.queueHistory(t.ID)
+	}
 }
 
 // onWelcome handles the welcome event.
@@ -230,12 +238,22 @@ func (s *Session) onWelcome(r *Welcome) {
 	if !s.StateEnabled {
 		return
 	}
-	s.Poll(enum.UpdateTypeDate, 0)
-	s.Poll(enum.UpdateTypeClientInfo, 0)
-	s.Poll(enum.UpdateTypeCompanyInfo, 0)
-	s.Poll(enum.UpdateTypeCompanyEconomy, 0)
-	s.Poll(enum.UpdateTypeCompanyStats, 0)
+	s.Poll(enum.UpdateTypeDate, PollAll)
+	errClients := s.Poll(enum.UpdateTypeClientInfo, PollAll)
+	errCompanies := s.Poll(enum.UpdateTypeCompanyInfo, PollAll)
+	s.Poll(enum.UpdateTypeCompanyEconomy, PollAll)
+	s.Poll(enum.UpdateTypeCompanyStats, PollAll)
+	if errClients != nil || errCompanies != nil {
+		return
+	}
 
+	// Anything in the state that the server doesn't tell us about is stale (i.e we were disconnected, or restored it).
+	// The replies are read after this event has been handled, so it isn't too late to mark it.
+	s.State.markUnconfirmed()
+	// The server answers in order, so by the time this ping is answered, every poll above has been too
+	if err := s.ping(pingTokenReconcile); err != nil {
+		s.log(LogWarning, "error sending reconcile ping, %s", err)
+	}
 }
 
 // onShutdown handles the server shutting down :(
@@ -244,13 +262,3 @@ func (s *Session) onShutdown(_ *Shutdown) {
 	s.Close()
 	s.reconnect()
 }
-
-// onRcon handles incoming Rcon packets and forwards them to the channel to be picked up by the Rcon goroutine.
-func (s *Session) onRcon(r *Rcon) {
-	s.rconChan <- &rconResp{rcon: r}
-}
-
-// onRconEnd handles incoming Rcon End packets and forwards them to the channel to be picked up by the Rcon goroutine.
-func (s *Session) onRconEnd(r *RconEnd) {
-	s.rconChan <- &rconResp{rconEnd: r}
-}
@@ -36,6 +36,7 @@ const (
 	rconEventType           = packetIndexServerRcon
 	rconEndEventType        = packetIndexServerRconEnd
 	shutdownEventType       = packetIndexServerShutdown
+	subscribedEventType     = 253 // internal handler
 	welcomeEventType        = packetIndexServerWelcome
 )
 
@@ -604,6 +605,21 @@ func (eh shutdownEventHandler) Handle(s *Session, i interface{}) {
 	}
 }
 
+// subscribedEventHandler is an event handler for Subscribed events.
+type subscribedEventHandler func(*Session, *Subscribed)
+
+// Type returns the event type for Subscribed events.
+func (eh subscribedEventHandler) Type() uint8 {
+	return subscribedEventType
+}
+
+// Handle is the handler for Subscribed events.
+func (eh subscribedEventHandler) Handle(s *Session, i interface{}) {
+	if t, ok := i.(*Subscribed); ok {
+		eh(s, t)
+	}
+}
+
 // welcomeEventHandler is an event handler for Welcome events.
 type welcomeEventHandler func(*Session, *Welcome)
 
@@ -686,6 +702,8 @@ func handlerForInterface(handler interface{}) EventHandler {
 		return rconEndEventHandler(v)
 	case func(*Session, *Shutdown):
 		return shutdownEventHandler(v)
+	case func(*Session, *Subscribed):
+		return subscribedEventHandler(v)
 	case func(*Session, *Welcome):
 		return welcomeEventHandler(v)
 	}
@@ -2,6 +2,7 @@ package admin
 
 import (
 	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
 )
 
 // This file contains all the possible structs that can be
@@ -17,6 +18,13 @@ type Connect struct{}
 // This is a synthetic event and is not dispatched by OpenTTD.
 type Disconnect struct{}
 
+// Subscribed is the data for a Subscribed event, fired every time update subscriptions are sent to the server
+// (i.e on every connection). It tells you what the server made of each subscription requested with RequestUpdates.
+// This is a synthetic event and is not dispatched by OpenTTD.
+type Subscribed struct {
+	Results []SubscriptionResult
+}
+
 // Event provides a basic initial struct for all game events.
 type Event struct {
 	Type    uint8  `json:"t"`
@@ -67,117 +75,118 @@ type Date struct { // Type 107
 
 // ClientJoin fires when a client joins.
 type ClientJoin struct { // Type 108
-	ID uint32 // ID of the new client.
+	ID enum.ClientID // ID of the new client.
 }
 
 // ClientInfo fires when information is provided for a client (usually polled).
 type ClientInfo struct { // Type 109
-	ID       uint32 // ID of the client.
-	Address  string // Network address of the client. (Can be nil if they're the host)
-	Name     string // Name of the client.
-	Language uint8  // Language of the client.
-	JoinDate uint32 // Date the client joined the game. (Can be nil if they're the host)
-	Company  uint8  // ID of the company the client is playing as (255 for spectators).
+	ID       enum.ClientID  // ID of the client.
+	Address  string         // Network address of the client. (Can be nil if they're the host)
+	Name     string         // Name of the client.
+	Language uint8          // Language of the client.
+	JoinDate uint32         // Date the client joined the game. (Can be nil if they're the host)
+	Company  enum.CompanyID // ID of the company the client is playing as (see CompanyID.IsSpectator()).
 }
 
 // ClientUpdate fires when a client changes the company it is playing as, or its name.
 type ClientUpdate struct { // Type 110
-	ID      uint32 // ID of the client.
-	Name    string // Name of the client.
-	Company uint8  // ID of the company the client is playing as (255 for spectators).
+	ID      enum.ClientID  // ID of the client.
+	Name    string         // Name of the client.
+	Company enum.CompanyID // ID of the company the client is playing as (see CompanyID.IsSpectator()).
 }
 
 // ClientQuit fires when a client leaves the game.
 type ClientQuit struct { // Type 111
-	ID uint32 // ID of the leaving client.
+	ID enum.ClientID // ID of the leaving client.
 }
 
 // ClientError fires when a client experiences an error (usually causes them to quit).
 type ClientError struct { // Type 112
-	ID    uint32        // ID of the client throwing the error.
+	ID    enum.ClientID // ID of the client throwing the error.
 	Error enum.NetError // Error the client made (see NetworkErrorCode).
 }
 
 // CompanyNew fires when a new company is founded.
 type CompanyNew struct { // Type 113
-	ID uint8 // ID of the new company.
+	ID enum.CompanyID // ID of the new company.
 }
 
 // CompanyInfo fires when information for a company is provided (usually polled).
 type CompanyInfo struct { // Type 114
-	ID        uint8  // ID of the company.
-	Name      string // Name of the company.
-	Manager   string // Name of the companies manager.
-	Colour    uint8  // Main company colour.
-	Password  bool   // Company is password protected.
-	StartDate uint32 // Year the company was inaugurated.
-	IsAI      bool   // Company is an AI.
+	ID        enum.CompanyID // ID of the company.
+	Name      string         // Name of the company.
+	Manager   string         // Name of the companies manager.
+	Colour    uint8          // Main company colour.
+	Password  bool           // Company is password protected.
+	StartDate uint32         // Year the company was inaugurated.
+	IsAI      bool           // Company is an AI.
 }
 
 // CompanyUpdate fires when a company changes something about its metadata, such as its name.
 type CompanyUpdate struct { // Type 115
-	ID                 uint8  // ID of the company.
-	Name               string // Name of the company.
-	Manager            string // Name of the company's manager.
-	Colour             uint8  // Main company colour.
-	Password           bool   // Company is password protected.
-	BankruptcyQuarters uint8  // Quarters of Bankruptcy.
-	Share1             uint8  // Owner of Share 1.
-	Share2             uint8  // Owner of Share 2.
-	Share3             uint8  // Owner of Share 3.
-	Share4             uint8  // Owner of Share 4.
+	ID                 enum.CompanyID // ID of the company.
+	Name               string         // Name of the company.
+	Manager            string         // Name of the company's manager.
+	Colour             uint8          // Main company colour.
+	Password           bool           // Company is password protected.
+	BankruptcyQuarters uint8          // Quarters of Bankruptcy.
+	Share1             uint8          // Owner of Share 1.
+	Share2             uint8          // Owner of Share 2.
+	Share3             uint8          // Owner of Share 3.
+	Share4             uint8          // Owner of Share 4.
 }
 
 // CompanyRemove fires when a company is removed from the game.
 type CompanyRemove struct { // Type 116
-	ID     uint8                    // ID of the company.
+	ID     enum.CompanyID           // ID of the company.
 	Reason enum.CompanyRemoveReason // Reason for being removed.
 }
 
 // CompanyEconomy fires when new economical data for the company is available (either polled or regularly, depends what you signed up for)
 type CompanyEconomy struct { // Type 117
-	ID                         uint8  // ID of the company.
-	Money                      uint64 // Money (cash in hand).
-	Loan                       uint64 // Loan.
-	Income                     int64  // Income.
-	CargoThisQuarter           uint16 // Delivered cargo (this quarter).
-	ValueLastQuarter           uint64 // Company value (last quarter).
-	PerformanceLastQuarter     uint16 // Performance (last quarter).
-	CargoLastQuarter           uint16 // Delivered cargo (last quarter).
-	ValuePreviousQuarter       uint64 // Company value (previous quarter).
-	PerformancePreviousQuarter uint16 // Performance (previous quarter).
-	CargoPreviousQuarter       uint16 // Delivered cargo (previous quarter).
+	ID                         enum.CompanyID // ID of the company.
+	Money                      util.Money     // Money (cash in hand).
+	Loan                       util.Money     // Loan.
+	Income                     util.Money     // Income.
+	CargoThisQuarter           uint16         // Delivered cargo (this quarter).
+	ValueLastQuarter           util.Money     // Company value (last quarter).
+	PerformanceLastQuarter     uint16         // Performance (last quarter).
+	CargoLastQuarter           uint16         // Delivered cargo (last quarter).
+	ValuePreviousQuarter       util.Money     // Company value (previous quarter).
+	PerformancePreviousQuarter uint16         // Performance (previous quarter).
+	CargoPreviousQuarter       uint16         // Delivered cargo (previous quarter).
 }
 
 // CompanyStats fires when new statistics for the company are available (either polled or regularly, depends what you signed up for)
 type CompanyStats struct { // Type 118
-	ID            uint8  // ID of the company.
-	Trains        uint16 // Number of trains.
-	Lorries       uint16 // Number of lorries.
-	Buses         uint16 // Number of busses.
-	Planes        uint16 // Number of planes.
-	Ships         uint16 // Number of ships.
-	TrainStations uint16 // Number of train stations.
-	LorryStations uint16 // Number of lorry stations.
-	BusStops      uint16 // Number of bus stops.
-	Airports      uint16 // Number of airports and heliports.
-	Harbours      uint16 // Number of harbours.
+	ID            enum.CompanyID // ID of the company.
+	Trains        uint16         // Number of trains.
+	Lorries       uint16         // Number of lorries.
+	Buses         uint16         // Number of busses.
+	Planes        uint16         // Number of planes.
+	Ships         uint16         // Number of ships.
+	TrainStations uint16         // Number of train stations.
+	LorryStations uint16         // Number of lorry stations.
+	BusStops      uint16         // Number of bus stops.
+	Airports      uint16         // Number of airports and heliports.
+	Harbours      uint16         // Number of harbours.
 }
 
 // Chat fires when any new chat message is posted.
 type Chat struct { // Type 119
-	Action      uint8  // Action such as NETWORK_ACTION_CHAT_CLIENT (see #NetworkAction).
-	Destination uint8  // Destination type such as DESTTYPE_BROADCAST (see #DestType).
-	ID          uint32 // ID of the client who sent this message.
-	Message     string // Message.
-	Money       uint64 // Money (only when it is a 'give money' action).
+	Action      enum.Action      // Action such as NETWORK_ACTION_CHAT_CLIENT (see #NetworkAction).
+	Destination enum.Destination // Destination type such as DESTTYPE_BROADCAST (see #DestType).
+	ID          enum.ClientID    // ID of the client who sent this message.
+	Message     string           // Message.
+	Money       util.Money       // Money (only when it is a 'give money' action).
 }
 
 // Rcon fires when a line of RCON output from the server is returned.
 // Use in conjunction with RconEnd to determine when a command has finished.
+// Colour is marshalled as its name (e.g "Silver") rather than a number.
 type Rcon struct { // Type 120
-	Colour uint16 // Colour as it would be used on the server or a client.
-	Output string // Output of the executed command.
+	Colour enum.TextColour // Colour as it would be used on the server or a client.
+	Output string          // Output of the executed command.
 }
 
 // Console fires when a console message is printed to the server output.
@@ -187,7 +196,8 @@ type Console struct { // Type 121
 }
 
 // CmdNames is a response to a request for command names.
-// You probably shouldn't track this event - use the state on the session.
+// You probably shouldn't track this event - use the state on the session (see State.CommandName).
+// The server may split the names over several of these.
 type CmdNames struct { // Type 122
 	/*
 	* NOTICE: Pack provided with this packet is not stable and will not be
@@ -208,14 +218,14 @@ type CmdLogging struct { // Type 123
 	*         Pack provided in this packet is for logging purposes only.
 	 */
 
-	Client    uint32 // ID of the client sending the command.
-	Company   uint8  // ID of the company (0..MAX_COMPANIES-1).
-	CommandID uint16 // ID of the command.
-	V1        uint32 // P1 (variable data passed to the command).
-	V2        uint32 // P2 (variable data passed to the command).
-	Tile      uint32 // Tile where this is taking place.
-	Message   string // Text passed to the command.
-	Frame     uint32 // Frame of execution.
+	Client    enum.ClientID  // ID of the client sending the command.
+	Company   enum.CompanyID // ID of the company (0..MAX_COMPANIES-1).
+	CommandID uint16         // ID of the command (see State.CommandName).
+	V1        uint32         // P1 (variable data passed to the command).
+	V2        uint32         // P2 (variable data passed to the command).
+	Tile      util.Tile      // Tile where this is taking place (see State.TileXY).
+	Message   string         // Text passed to the command.
+	Frame     uint32         // Frame of execution.
 }
 
 // Gamescript is some data that was sent by a GameScript running on the server.
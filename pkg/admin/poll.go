@@ -0,0 +1,129 @@
+package admin
+
+import (
+	"context"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+)
+
+// PollAll can be given to Poll as the ID to receive updates for all clients or companies at once.
+const PollAll = ^uint32(0)
+
+// PollClient polls the server for information about the given client, or all clients if id is ClientIDInvalid.
+func (s *Session) PollClient(id enum.ClientID) (err error) {
+	if !id.IsValid() {
+		return s.Poll(enum.UpdateTypeClientInfo, PollAll)
+	}
+	return s.Poll(enum.UpdateTypeClientInfo, uint32(id))
+}
+
+// PollCompany polls the server for information about the given company, or all companies if id isn't a valid
+// company (e.g CompanyIDSpectator).
+func (s *Session) PollCompany(id enum.CompanyID) (err error) {
+	if !id.IsValid() {
+		return s.Poll(enum.UpdateTypeCompanyInfo, PollAll)
+	}
+	return s.Poll(enum.UpdateTypeCompanyInfo, uint32(id))
+}
+
+// RefreshClient polls the server for up to date information about the given client, and waits for the reply.
+// If state tracking is enabled, the State has been updated by the time this returns.
+// The server doesn't reply at all for clients that don't exist, so make sure ctx has a deadline.
+// Don't call this from an event handler while SyncEvents is set, as the reply can't be handled until it returns.
+func (s *Session) RefreshClient(ctx context.Context, id enum.ClientID) (cli Client, err error) {
+	infos := make(chan *ClientInfo, 1)
+	remove := s.addEventHandler(clientInfoEventHandler(func(_ *Session, r *ClientInfo) {
+		if r.ID == id {
+			select {
+			case infos <- r:
+			default:
+			}
+		}
+	}))
+	defer remove()
+
+	if err = s.PollClient(id); err != nil {
+		return cli, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return cli, ctx.Err()
+	case r := <-infos:
+		cli.ID = r.ID
+		cli.applyClientInfo(r)
+		return cli, nil
+	}
+}
+
+// RefreshCompany polls the server for up to date information, economy and statistics about the given company,
+// and waits for all of the replies.
+// If state tracking is enabled, the State has been updated by the time this returns.
+// The server doesn't reply at all for companies that don't exist, so make sure ctx has a deadline.
+// Don't call this from an event handler while SyncEvents is set, as the replies can't be handled until it returns.
+func (s *Session) RefreshCompany(ctx context.Context, id enum.CompanyID) (com Company, err error) {
+	infos := make(chan *CompanyInfo, 1)
+	economies := make(chan *CompanyEconomy, 1)
+	stats := make(chan *CompanyStats, 1)
+
+	removeInfo := s.addEventHandler(companyInfoEventHandler(func(_ *Session, r *CompanyInfo) {
+		if r.ID == id {
+			select {
+			case infos <- r:
+			default:
+			}
+		}
+	}))
+	defer removeInfo()
+	removeEconomy := s.addEventHandler(companyEconomyEventHandler(func(_ *Session, r *CompanyEconomy) {
+		if r.ID == id {
+			select {
+			case economies <- r:
+			default:
+			}
+		}
+	}))
+	defer removeEconomy()
+	removeStats := s.addEventHandler(companyStatsEventHandler(func(_ *Session, r *CompanyStats) {
+		if r.ID == id {
+			select {
+			case stats <- r:
+			default:
+			}
+		}
+	}))
+	defer removeStats()
+
+	// Start from what we already know, as some details (e.g bankruptcy) are only ever sent as updates
+	if known, ok := s.State.Company(id); ok {
+		com = known
+	}
+	com.ID = id
+
+	// The server always answers economy and statistics polls for every company, so the ID is only used for the info
+	if err = s.PollCompany(id); err != nil {
+		return com, err
+	}
+	if err = s.Poll(enum.UpdateTypeCompanyEconomy, PollAll); err != nil {
+		return com, err
+	}
+	if err = s.Poll(enum.UpdateTypeCompanyStats, PollAll); err != nil {
+		return com, err
+	}
+
+	for infos != nil || economies != nil || stats != nil {
+		select {
+		case <-ctx.Done():
+			return com, ctx.Err()
+		case r := <-infos:
+			com.applyCompanyInfo(r)
+			infos = nil
+		case r := <-economies:
+			com.applyCompanyEconomy(r)
+			economies = nil
+		case r := <-stats:
+			com.applyCompanyStats(r)
+			stats = nil
+		}
+	}
+	return com, nil
+}
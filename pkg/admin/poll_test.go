@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/ropenttd/gopenttd/internal/openttd_packets_admin"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// newCapturingConnection connects the session to a local listener, which passes on each packet it's sent
+// (starting with the packet type) to the returned channel.
+func newCapturingConnection(t *testing.T, s *Session) <-chan []byte {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	sent := make(chan []byte, 16)
+	go func() {
+		defer close(sent)
+		conn, err := l.Accept()
+		l.Close()
+		if err != nil {
+			return
+		}
+		for {
+			length := make([]byte, 2)
+			if _, err := io.ReadFull(conn, length); err != nil {
+				return
+			}
+			packet := make([]byte, binary.LittleEndian.Uint16(length)-2)
+			if _, err := io.ReadFull(conn, packet); err != nil {
+				return
+			}
+			sent <- packet
+		}
+	}()
+
+	s.conn, err = net.DialTCP("tcp", nil, l.Addr().(*net.TCPAddr))
+	assert.NoError(t, err)
+	return sent
+}
+
+// pollPacket returns the packet Poll sends for the given update type and ID.
+func pollPacket(ut enum.UpdateType, id uint32) []byte {
+	packet := []byte{byte(openttd_packets_admin.PacketAdminPoll), byte(ut), 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(packet[2:], id)
+	return packet
+}
+
+func TestRefreshClient(t *testing.T) {
+	s := newTestSession()
+	sent := newCapturingConnection(t, s)
+	defer s.conn.Close()
+	s.pollrates = map[enum.UpdateType]uint16{enum.UpdateTypeClientInfo: uint16(enum.UpdateFrequencyPoll)}
+
+	type result struct {
+		cli Client
+		err error
+	}
+	results := make(chan result)
+	refresh := func(ctx context.Context, id enum.ClientID) {
+		cli, err := s.RefreshClient(ctx, id)
+		results <- result{cli, err}
+	}
+
+	go refresh(context.Background(), 5)
+	assert.Equal(t, pollPacket(enum.UpdateTypeClientInfo, 5), <-sent)
+	// Replies about other clients are ignored
+	s.handleEvent(clientInfoEventType, &ClientInfo{ID: 4, Name: "Bob"})
+	s.handleEvent(clientInfoEventType, &ClientInfo{ID: 5, Name: "Alice", Company: 2})
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, "Alice", res.cli.Name)
+	assert.Equal(t, enum.CompanyID(2), res.cli.Company)
+
+	// The server never answers for clients that don't exist
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	go refresh(ctx, 9)
+	assert.Equal(t, pollPacket(enum.UpdateTypeClientInfo, 9), <-sent)
+	res = <-results
+	assert.Equal(t, context.DeadlineExceeded, res.err)
+
+	// We can't poll for things the server doesn't let us
+	s.pollrates = map[enum.UpdateType]uint16{}
+	_, err := s.RefreshClient(context.Background(), 5)
+	assert.Equal(t, ErrInvalidUpdateFrequency, err)
+}
+
+func TestRefreshCompany(t *testing.T) {
+	s := newTestSession()
+	sent := newCapturingConnection(t, s)
+	defer s.conn.Close()
+	poll := uint16(enum.UpdateFrequencyPoll)
+	s.pollrates = map[enum.UpdateType]uint16{
+		enum.UpdateTypeCompanyInfo:    poll,
+		enum.UpdateTypeCompanyEconomy: poll,
+		enum.UpdateTypeCompanyStats:   poll,
+	}
+
+	type result struct {
+		com Company
+		err error
+	}
+	results := make(chan result)
+	refresh := func(ctx context.Context, id enum.CompanyID) {
+		com, err := s.RefreshCompany(ctx, id)
+		results <- result{com, err}
+	}
+
+	go refresh(context.Background(), 2)
+	assert.Equal(t, pollPacket(enum.UpdateTypeCompanyInfo, 2), <-sent)
+	assert.Equal(t, pollPacket(enum.UpdateTypeCompanyEconomy, PollAll), <-sent)
+	assert.Equal(t, pollPacket(enum.UpdateTypeCompanyStats, PollAll), <-sent)
+
+	// It waits for all three replies
+	s.handleEvent(companyInfoEventType, &CompanyInfo{ID: 2, Name: "Alice Transport"})
+	s.handleEvent(companyEconomyEventType, &CompanyEconomy{ID: 1, Money: 5})
+	s.handleEvent(companyEconomyEventType, &CompanyEconomy{ID: 2, Money: 1000})
+	select {
+	case <-results:
+		t.Fatal("returned before all the replies arrived")
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.handleEvent(companyStatsEventType, &CompanyStats{ID: 2})
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, "Alice Transport", res.com.Name)
+	assert.EqualValues(t, 1000, res.com.Money)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	go refresh(ctx, 7)
+	assert.Equal(t, pollPacket(enum.UpdateTypeCompanyInfo, 7), <-sent)
+	<-sent
+	<-sent
+	res = <-results
+	assert.Equal(t, context.DeadlineExceeded, res.err)
+}
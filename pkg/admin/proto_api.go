@@ -28,6 +28,9 @@ func (s *Session) Open() error {
 		return ErrAlreadyConnected
 	}
 
+	// Pick up where we left off, if we were restarted
+	s.restoreState()
+
 	// Connect to the server
 	server := fmt.Sprintf("%s:%d", s.Hostname, s.Port)
 	serverAddr, err := net.ResolveTCPAddr("tcp", server)
@@ -102,24 +105,25 @@ func (s *Session) Open() error {
 		s.log(LogWarning, "Expected WELCOME, instead got:\n%#v\n", e)
 	}
 
-	s.log(LogInformational, "We are now connected to OpenTTD, emitting connect event")
-	s.handleEvent(connectEventType, &Connect{})
-
-	// Now we have protocol information, send any Update Frequencies that are cached in the session
-	// i.e if we got disconnected
-	for k, v := range s.UpdateFrequencies {
-		go s.RequestUpdates(k, v)
-	}
-
 	// Create listening chan outside of listen, as it needs to happen inside the
 	// mutex lock and needs to exist before calling heartbeat and listen
 	// go routines.
 	s.listening = make(chan interface{})
 
+	// Now we have protocol information, send any update subscriptions that have been requested
+	// i.e if we got disconnected
+	s.reconcileSubscriptions(s.listening)
+
+	s.log(LogInformational, "We are now connected to OpenTTD, emitting connect event")
+	s.handleEvent(connectEventType, &Connect{})
+
 	// Start sending heartbeats and reading messages from the game.
 	go s.heartbeat(s.conn, s.listening)
 	go s.listen(s.conn, s.listening)
 	go s.handleRconRequests(s.listening)
+	if s.Store != nil {
+		go s.persistState(s.listening)
+	}
 
 	s.log(LogInformational, "exiting")
 	return nil
@@ -128,7 +132,14 @@ func (s *Session) Open() error {
 func (s *Session) Close() (err error) {
 	s.Lock()
 	s.Ready = false
+	// Take the final snapshot now, but leave writing it until we've let go of the lock
+	var ss *StateSnapshot
+	if s.Store != nil && s.StateEnabled {
+		snapshot := s.State.Snapshot()
+		ss = &snapshot
+	}
 	// Be polite, if we can
+	s.connMutex.Lock()
 	if s.conn != nil {
 		writePacketToTcpConn(s.conn, packets.AdminQuit{})
 		// Close the connection
@@ -137,6 +148,12 @@ func (s *Session) Close() (err error) {
 
 	// Nil out the connection
 	s.conn = nil
+	s.connMutex.Unlock()
+
+	// Forget what the server supports, until we hear from it again
+	s.pollratesMu.Lock()
+	s.pollrates = nil
+	s.pollratesMu.Unlock()
 
 	// Close the listener
 	close(s.listening)
@@ -145,9 +162,22 @@ func (s *Session) Close() (err error) {
 	s.handleEvent(disconnectEventType, &Disconnect{})
 	s.Unlock()
 
+	if ss != nil {
+		s.flushHistory()
+		s.saveSnapshot(*ss)
+	}
 	return
 }
 
+// ping sends a ping with the given token to the server.
+func (s *Session) ping(token uint32) (err error) {
+	s.connMutex.Lock()
+	defer s.connMutex.Unlock()
+
+	err = writePacketToTcpConn(s.conn, packets.AdminPing{Token: token})
+	return err
+}
+
 // identify sends the authentication packet to the server
 func (s *Session) identify() (err error) {
 
@@ -168,6 +198,9 @@ func (s *Session) identify() (err error) {
 // isValidUpdateFrequency checks whether the given UpdateType can be requested at the given Frequency
 // This requires valid data from the Protocol packet (i.e we have to be connected)
 func (s *Session) isValidUpdateFrequency(t enum.UpdateType, f enum.UpdateFrequency) bool {
+	s.pollratesMu.RLock()
+	defer s.pollratesMu.RUnlock()
+
 	if s.pollrates == nil {
 		// We have no idea.
 		return false
@@ -180,39 +213,10 @@ func (s *Session) isValidUpdateFrequency(t enum.UpdateType, f enum.UpdateFrequen
 	}
 }
 
-// RequestUpdates sends a request to receive updates of the given type from the server at a given interval.
-// Supplying a frequency of POLL is invalid and will return an error - use Session.Poll()
-func (s *Session) RequestUpdates(t enum.UpdateType, f enum.UpdateFrequency) (err error) {
-	if f == enum.UpdateFrequencyPoll || !s.isValidUpdateFrequency(t, f) {
-		// The server will ignore us or refuse us
-		return ErrInvalidUpdateFrequency
-	}
-
-	data := packets.AdminUpdateFrequency{
-		Type:      t,
-		Frequency: f,
-	}
-	s.connMutex.Lock()
-	defer s.connMutex.Unlock()
-
-	err = writePacketToTcpConn(s.conn, data)
-
-	if err != nil {
-		return err
-	}
-
-	// Cache the requested update frequency if we successfully sent the update
-	if s.UpdateFrequencies == nil {
-		s.UpdateFrequencies = map[enum.UpdateType]enum.UpdateFrequency{}
-	}
-
-	s.UpdateFrequencies[t] = f
-
-	return
-
-}
-
 // Poll sends a request to receive one update for the given UpdateType and ID.
+// The ID is a client ID for UpdateTypeClientInfo, or a company ID for UpdateTypeCompanyInfo - use PollAll to
+// receive updates for all of them, or PollClient and PollCompany to poll with a typed ID. Other update types ignore
+// the ID.
 // If you can't poll for the given UpdateType, this returns an error.
 func (s *Session) Poll(t enum.UpdateType, id uint32) (err error) {
 	if !s.isValidUpdateFrequency(t, enum.UpdateFrequencyPoll) {
@@ -221,22 +225,7 @@ func (s *Session) Poll(t enum.UpdateType, id uint32) (err error) {
 	}
 	data := packets.AdminPoll{
 		Type: t,
-		ID:   ^uint32(0),
-	}
-	s.connMutex.Lock()
-	defer s.connMutex.Unlock()
-
-	err = writePacketToTcpConn(s.conn, data)
-	return err
-}
-
-// Chat sends a chat message (who'dve thought it?)
-func (s *Session) Chat(act enum.Action, dest enum.Destination, destID uint32, message string) (err error) {
-	data := packets.AdminChat{
-		Action:        act,
-		Destination:   dest,
-		DestinationID: destID,
-		Message:       message,
+		ID:   id,
 	}
 	s.connMutex.Lock()
 	defer s.connMutex.Unlock()
@@ -346,6 +335,12 @@ func (s *Session) reconnect() {
 // FailedPongs is the Number of pong intervals to wait until forcing a connection restart.
 const FailedPongs = 6
 
+// Tokens sent with pings, so we can tell what the pong is for.
+const (
+	pingTokenHeartbeat uint32 = 1
+	pingTokenReconcile uint32 = 2
+)
+
 // HeartbeatLatency returns the latency between heartbeat acknowledgement and heartbeat send.
 func (s *Session) HeartbeatLatency() time.Duration {
 
@@ -375,8 +370,7 @@ func (s *Session) heartbeat(conn *net.TCPConn, listening <-chan interface{}) {
 		s.log(LogDebug, "sending game ping")
 		s.connMutex.Lock()
 		s.LastPing = time.Now().UTC()
-		// very lazy implementation of token for very lazy people
-		err = writePacketToTcpConn(conn, packets.AdminPing{Token: 1})
+		err = writePacketToTcpConn(conn, packets.AdminPing{Token: pingTokenHeartbeat})
 		s.connMutex.Unlock()
 		if err != nil || time.Now().UTC().Sub(last) > (heartbeatInterval*FailedPongs) {
 			if err != nil {
@@ -421,6 +415,14 @@ func (s *Session) onEvent(messageType uint8, message []byte) (*Event, error) {
 	s.log(LogDebug, "Type: %d, Data: %s\n\n", e.Type, string(e.RawData))
 
 	if e.Type == packetIndexServerPong {
+		if len(e.RawData) >= 4 && binary.LittleEndian.Uint32(e.RawData) == pingTokenReconcile {
+			// Every poll sent on welcome has been answered
+			s.log(LogDebug, "got reconcile pong")
+			if s.StateEnabled {
+				s.State.pruneUnconfirmed(s)
+			}
+			return e, nil
+		}
 		s.Lock()
 		s.LastPong = time.Now().UTC()
 		s.Unlock()
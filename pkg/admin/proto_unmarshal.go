@@ -32,12 +32,13 @@ func ottdUnmarshalData(buffer *bytes.Buffer, val reflect.Value, set bool) (gen i
 	switch val.Kind() {
 	// binary.Read() doesn't appear to work here (always returns 0?) so do things the long way
 	// i.e binary.Read(buffer, binary.LittleEndian, nv)
+	// Values are set by kind rather than by type, so that named types (e.g util.Money) can be unmarshalled into.
 	case reflect.Bool:
 		var nv bool
 		if buffer.Len() >= 1 {
 			nv = uint8(buffer.Next(1)[0]) != 0
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetBool(nv)
 			}
 		}
 		return nv
@@ -46,7 +47,7 @@ func ottdUnmarshalData(buffer *bytes.Buffer, val reflect.Value, set bool) (gen i
 		if buffer.Len() >= 1 {
 			nv = uint8(buffer.Next(1)[0])
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetUint(uint64(nv))
 			}
 		}
 		return nv
@@ -55,34 +56,34 @@ func ottdUnmarshalData(buffer *bytes.Buffer, val reflect.Value, set bool) (gen i
 		if buffer.Len() >= 2 {
 			nv = binary.LittleEndian.Uint16(buffer.Next(2))
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetUint(uint64(nv))
 			}
 		}
 		return nv
 	case reflect.Uint32:
 		var nv uint32
-		if buffer.Len() >= 1 {
+		if buffer.Len() >= 4 {
 			nv = binary.LittleEndian.Uint32(buffer.Next(4))
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetUint(uint64(nv))
 			}
 		}
 		return nv
 	case reflect.Int64:
 		var nv int64
-		if buffer.Len() >= 64 {
-			binary.Read(buffer, binary.LittleEndian, &nv)
+		if buffer.Len() >= 8 {
+			nv = int64(binary.LittleEndian.Uint64(buffer.Next(8)))
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetInt(nv)
 			}
 		}
 		return nv
 	case reflect.Uint64:
 		var nv uint64
-		if buffer.Len() >= 64 {
+		if buffer.Len() >= 8 {
 			nv = binary.LittleEndian.Uint64(buffer.Next(8))
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetUint(nv)
 			}
 		}
 		return nv
@@ -92,7 +93,7 @@ func ottdUnmarshalData(buffer *bytes.Buffer, val reflect.Value, set bool) (gen i
 			nvBytes, _ := buffer.ReadBytes(byte(0))
 			nv = string(bytes.Trim(nvBytes, "\x00"))
 			if set {
-				val.Set(reflect.ValueOf(nv))
+				val.SetString(nv)
 			}
 		}
 		return nv
@@ -1,39 +1,222 @@
 package admin
 
-import "github.com/ropenttd/gopenttd/pkg/admin/packets"
+import (
+	"context"
+	"github.com/ropenttd/gopenttd/pkg/admin/packets"
+	"sync"
+	"time"
+)
 
 // RCON related stuff is dealt with in this file to help keep things a little tidier.
 
+// DefaultRconTimeout is the default time to wait for the server to finish an RCON command.
+const DefaultRconTimeout = 30 * time.Second
+
+// An rconRequest is an RCON command waiting to be sent, or waiting for its output.
 type rconRequest struct {
-	Command      string `json:"command"`
-	responseChan chan []Rcon
+	Command string `json:"command"`
+	ctx     context.Context
+
+	// The output so far, and the result once done is closed. Guarded by Session.rconMu.
+	output []Rcon
+	err    error
+	// Set once nobody is waiting for the output any more.
+	abandoned bool
+
+	// Signalled whenever there's new output.
+	notify   chan struct{}
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func newRconRequest(ctx context.Context, command string) *rconRequest {
+	return &rconRequest{Command: command, ctx: ctx, notify: make(chan struct{}, 1), done: make(chan struct{})}
+}
+
+// finished returns whether the request is done.
+func (r *rconRequest) finished() bool {
+	select {
+	case <-r.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// abandon throws away the output of the request, which still has to run to completion to keep the server's output
+// in step with our queue.
+// Session.rconMu must be held when calling this.
+func (r *rconRequest) abandon() {
+	r.abandoned = true
+	r.output = nil
 }
 
-type rconResp struct {
-	rcon    *Rcon
-	rconEnd *RconEnd
+// finish marks the request as done, with the given error.
+// Session.rconMu must be held when calling this.
+func (r *rconRequest) finish(err error) {
+	r.doneOnce.Do(func() {
+		r.err = err
+		close(r.done)
+	})
+}
+
+// queueRcon hands the request over to handleRconRequests.
+// ErrDisconnected is returned straight away if we aren't connected, as nothing would ever take it.
+func (s *Session) queueRcon(ctx context.Context, req *rconRequest) error {
+	s.RLock()
+	listening := s.listening
+	s.RUnlock()
+	if listening == nil {
+		return ErrDisconnected
+	}
+
+	select {
+	case s.rconQueue <- req:
+		return nil
+	case <-listening:
+		return ErrDisconnected
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // Rcon sends a non-blocking RCON command to the server.
-// Use this when you don't care what the result is - if you do, use RconSync(command).
+// Use this when you don't care what the result is - if you do, use RconContext(ctx, command).
+// ErrDisconnected is returned if we aren't connected.
 func (s *Session) Rcon(command string) (err error) {
 	// we have to add this to the queue because the handleRconRequests queue will get out of step with commands otherwise
+	ctx, cancel := context.WithTimeout(context.Background(), s.rconTimeout())
+	defer cancel()
 
-	obj := rconRequest{Command: command}
-	s.rconQueue <- &obj
-	return nil
+	// Nobody is going to read the output
+	req := newRconRequest(context.Background(), command)
+	req.abandoned = true
+
+	err = s.queueRcon(ctx, req)
+	if err == context.DeadlineExceeded {
+		err = ErrRconTimeout
+	}
+	return err
 }
 
 // RconSync sends a blocking RCON command to the server, waits for a response, then returns a set of response packets.
-// Please note: This will block your thread until we get a complete response from the server!
+// Please note: This will block your thread until we get a complete response from the server, or RconTimeout passes!
 // If you don't care about the result, use Rcon(command).
 func (s *Session) RconSync(command string) (ret []Rcon, err error) {
-	rchan := make(chan []Rcon)
-	obj := rconRequest{Command: command, responseChan: rchan}
-	s.rconQueue <- &obj
-	// Block on a response
-	ret = <-obj.responseChan
-	return ret, nil
+	ctx, cancel := context.WithTimeout(context.Background(), s.rconTimeout())
+	defer cancel()
+
+	ret, err = s.RconContext(ctx, command)
+	if err == context.DeadlineExceeded {
+		err = ErrRconTimeout
+	}
+	return ret, err
+}
+
+// RconContext sends an RCON command to the server, and returns its output once the server has finished running it.
+// If ctx is done first, its error is returned. Otherwise, an error is returned if the command couldn't be sent, we
+// aren't connected or were disconnected before it finished (ErrDisconnected), or the server didn't finish it within
+// RconTimeout.
+// Don't call this from an event handler while SyncEvents is set, as the output can't be handled until it returns.
+func (s *Session) RconContext(ctx context.Context, command string) (ret []Rcon, err error) {
+	req := newRconRequest(ctx, command)
+	if err = s.queueRcon(ctx, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-req.done:
+		s.rconMu.Lock()
+		defer s.rconMu.Unlock()
+		return req.output, req.err
+	case <-ctx.Done():
+		// The request stays in place until the server is done with it, so its output doesn't end up in the next one
+		s.rconMu.Lock()
+		req.abandon()
+		s.rconMu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// RconOutput is the output of an RCON command started with RconStream.
+type RconOutput struct {
+	// Lines receives each line of output as it arrives. It is closed when the command finishes, or fails.
+	Lines <-chan Rcon
+
+	err  error
+	done chan struct{}
+}
+
+// Err waits for the command to finish, and returns nil if it did so successfully.
+// Otherwise, it returns the same errors as RconContext.
+func (o *RconOutput) Err() error {
+	<-o.done
+	return o.err
+}
+
+// RconStream sends an RCON command to the server, and returns its output line by line as it arrives.
+// This is handy for commands with a lot of output, e.g list_settings. Keep reading Lines until it's closed (or cancel
+// ctx), then check Err() to see if the command finished successfully.
+func (s *Session) RconStream(ctx context.Context, command string) *RconOutput {
+	lines := make(chan Rcon)
+	out := &RconOutput{Lines: lines, done: make(chan struct{})}
+	go s.streamRcon(ctx, newRconRequest(ctx, command), lines, out)
+	return out
+}
+
+// streamRcon queues the request, and passes its output on to the lines channel until it's done.
+func (s *Session) streamRcon(ctx context.Context, req *rconRequest, lines chan<- Rcon, out *RconOutput) {
+	defer close(out.done)
+	defer close(lines)
+
+	if out.err = s.queueRcon(ctx, req); out.err != nil {
+		return
+	}
+
+	for {
+		// Take everything we have so far, and check whether that's the lot while we're at it
+		s.rconMu.Lock()
+		pending := req.output
+		req.output = nil
+		finished := req.finished()
+		s.rconMu.Unlock()
+
+		for _, l := range pending {
+			select {
+			case lines <- l:
+			case <-ctx.Done():
+				s.rconMu.Lock()
+				req.abandon()
+				s.rconMu.Unlock()
+				out.err = ctx.Err()
+				return
+			}
+		}
+
+		if finished {
+			out.err = req.err
+			return
+		}
+
+		select {
+		case <-req.notify:
+		case <-req.done:
+		case <-ctx.Done():
+			s.rconMu.Lock()
+			req.abandon()
+			s.rconMu.Unlock()
+			out.err = ctx.Err()
+			return
+		}
+	}
+}
+
+// rconTimeout returns the time to wait for an RCON command to finish.
+func (s *Session) rconTimeout() time.Duration {
+	if s.RconTimeout <= 0 {
+		return DefaultRconTimeout
+	}
+	return s.RconTimeout
 }
 
 func (s *Session) sendRconCommand(command string) (err error) {
@@ -47,6 +230,8 @@ func (s *Session) sendRconCommand(command string) (err error) {
 	return err
 }
 
+// handleRconRequests sends queued RCON commands to the server one at a time, as the server doesn't tell us which
+// command its output belongs to until the end.
 func (s *Session) handleRconRequests(listening <-chan interface{}) {
 
 	s.log(LogDebug, "called")
@@ -55,33 +240,89 @@ func (s *Session) handleRconRequests(listening <-chan interface{}) {
 		select {
 		case <-listening:
 			return
-		case cmd := <-s.rconQueue:
-			// Send it
-			err := s.sendRconCommand(cmd.Command)
-			if err != nil {
-				if cmd.responseChan != nil {
-					cmd.responseChan <- []Rcon{}
-				}
-				continue
-			}
-			var data []Rcon
-			var run = true
-			for run {
-				v := <-s.rconChan
-				switch {
-				case v.rcon != nil:
-					// not an ending packet
-					data = append(data, *v.rcon)
-				case v.rconEnd != nil:
-					// ending packet
-					if v.rconEnd.Command == cmd.Command {
-						run = false
-					}
-				}
-			}
-			if cmd.responseChan != nil {
-				cmd.responseChan <- data
-			}
+		case req := <-s.rconQueue:
+			s.runRconRequest(req, listening)
 		}
 	}
 }
+
+// runRconRequest sends the command and waits for the server to finish it.
+func (s *Session) runRconRequest(req *rconRequest, listening <-chan interface{}) {
+	if err := req.ctx.Err(); err != nil {
+		// Nobody is waiting for this any more
+		s.rconMu.Lock()
+		req.finish(err)
+		s.rconMu.Unlock()
+		return
+	}
+
+	s.rconMu.Lock()
+	s.rconCurrent = req
+	s.rconMu.Unlock()
+
+	if err := s.sendRconCommand(req.Command); err != nil {
+		s.log(LogWarning, "error sending rcon command %q, %s", req.Command, err)
+		s.endRconRequest(req, err)
+		return
+	}
+
+	timeout := time.NewTimer(s.rconTimeout())
+	defer timeout.Stop()
+
+	select {
+	case <-req.done:
+	case <-listening:
+		s.endRconRequest(req, ErrDisconnected)
+	case <-timeout.C:
+		s.log(LogWarning, "timed out waiting for rcon command %q to finish", req.Command)
+		s.endRconRequest(req, ErrRconTimeout)
+	}
+}
+
+// endRconRequest finishes the request with the given error, if it hasn't finished already.
+func (s *Session) endRconRequest(req *rconRequest, err error) {
+	s.rconMu.Lock()
+	defer s.rconMu.Unlock()
+
+	if s.rconCurrent == req {
+		s.rconCurrent = nil
+	}
+	req.finish(err)
+}
+
+// onRcon adds incoming Rcon output to the current RCON request.
+// This is called from the read path, so it mustn't block.
+func (s *Session) onRcon(r *Rcon) {
+	s.rconMu.Lock()
+	defer s.rconMu.Unlock()
+
+	if s.rconCurrent == nil {
+		// Nobody asked for this, but handlers still get to see it
+		s.log(LogDebug, "got rcon output with no outstanding command")
+		return
+	}
+	if s.rconCurrent.abandoned {
+		return
+	}
+	s.rconCurrent.output = append(s.rconCurrent.output, *r)
+
+	select {
+	case s.rconCurrent.notify <- struct{}{}:
+	default:
+		// Already signalled
+	}
+}
+
+// onRconEnd finishes the current RCON request, if the command matches.
+// This is called from the read path, so it mustn't block.
+func (s *Session) onRconEnd(r *RconEnd) {
+	s.rconMu.Lock()
+	defer s.rconMu.Unlock()
+
+	if s.rconCurrent == nil || s.rconCurrent.Command != r.Command {
+		s.log(LogDebug, "got rcon end for %q, which isn't the outstanding command", r.Command)
+		return
+	}
+	s.rconCurrent.finish(nil)
+	s.rconCurrent = nil
+}
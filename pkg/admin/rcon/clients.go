@@ -0,0 +1,124 @@
+package rcon
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
+	"net"
+	"regexp"
+	"strconv"
+)
+
+// consoleCompany converts a company number as shown on the console (1-based, 255 for spectators) to a CompanyID.
+func consoleCompany(n uint64) enum.CompanyID {
+	if n > enum.MaxCompanies {
+		return enum.CompanyIDSpectator
+	}
+	// Anything that isn't a company is a spectator
+	id, _ := enum.CompanyIDFromNumber(uint8(n))
+	return id
+}
+
+// A Client is a line of output from the "clients" command.
+type Client struct {
+	ID      enum.ClientID  `json:"id"`
+	Name    string         `json:"name"`
+	Company enum.CompanyID `json:"company"`
+	// IP is the client's address, or nil for the server itself.
+	IP net.IP `json:"ip"`
+}
+
+var clientLine = regexp.MustCompile(`^Client #(\d+)\s+name: '(.*)'\s+company: (\d+)\s+IP: (\S+)$`)
+
+// ParseClients parses the output of the "clients" command.
+func ParseClients(output []string) (clients []Client, err error) {
+	for _, l := range splitLines(output) {
+		m := clientLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		id, _ := strconv.ParseUint(m[1], 10, 32)
+		company, _ := strconv.ParseUint(m[3], 10, 8)
+		clients = append(clients, Client{
+			ID:      enum.ClientID(id),
+			Name:    m[2],
+			Company: consoleCompany(company),
+			IP:      net.ParseIP(m[4]),
+		})
+	}
+	return clients, nil
+}
+
+// A Company is a line of output from the "companies" command.
+type Company struct {
+	ID enum.CompanyID `json:"id"`
+	// Colour is the name of the company colour, e.g "Dark Blue".
+	Colour      string     `json:"colour"`
+	Name        string     `json:"name"`
+	YearFounded int        `json:"year_founded"`
+	Money       util.Money `json:"money"`
+	Loan        util.Money `json:"loan"`
+	Value       util.Money `json:"value"`
+	// The number of each kind of vehicle the company has.
+	Trains       int `json:"trains"`
+	RoadVehicles int `json:"road_vehicles"`
+	Planes       int `json:"planes"`
+	Ships        int `json:"ships"`
+	// Passworded is set if the company is password protected (servers before OpenTTD 14 only).
+	Passworded bool `json:"passworded"`
+}
+
+var companyLine = regexp.MustCompile(`^#:(\d+)\((.*?)\) Company Name: '(.*)'\s+Year Founded: (\d+)\s+Money: (-?\d+)\s+Loan: (-?\d+)\s+Value: (-?\d+)\s+\(T:(\d+), R:(\d+), P:(\d+), S:(\d+)\)\s*(\w*)$`)
+
+// ParseCompanies parses the output of the "companies" command.
+func ParseCompanies(output []string) (companies []Company, err error) {
+	for _, l := range splitLines(output) {
+		m := companyLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.ParseUint(m[1], 10, 8)
+		com := Company{
+			ID:         consoleCompany(n),
+			Colour:     m[2],
+			Name:       m[3],
+			Passworded: m[12] == "protected",
+		}
+		com.YearFounded, _ = strconv.Atoi(m[4])
+		com.Money = parseMoney(m[5])
+		com.Loan = parseMoney(m[6])
+		com.Value = parseMoney(m[7])
+		com.Trains, _ = strconv.Atoi(m[8])
+		com.RoadVehicles, _ = strconv.Atoi(m[9])
+		com.Planes, _ = strconv.Atoi(m[10])
+		com.Ships, _ = strconv.Atoi(m[11])
+		companies = append(companies, com)
+	}
+	return companies, nil
+}
+
+func parseMoney(s string) util.Money {
+	m, _ := strconv.ParseInt(s, 10, 64)
+	return util.Money(m)
+}
+
+// A Ban is an entry in the ban list, as shown by the "banlist" command.
+type Ban struct {
+	// Index is the position of the entry in the list, which can be passed to "unban".
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+}
+
+var banLine = regexp.MustCompile(`^\s*(\d+)\) (.+)$`)
+
+// ParseBanList parses the output of the "banlist" command.
+func ParseBanList(output []string) (bans []Ban, err error) {
+	for _, l := range splitLines(output) {
+		m := banLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		i, _ := strconv.Atoi(m[1])
+		bans = append(bans, Ban{Index: i, Address: m[2]})
+	}
+	return bans, nil
+}
@@ -0,0 +1,27 @@
+package rcon
+
+import (
+	"regexp"
+	"strings"
+)
+
+var commandListLine = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ParseCommandList parses the output of the "list_cmds" command (or "list_aliases"), returning the names of the
+// console commands.
+func ParseCommandList(output []string) (commands []string, err error) {
+	for _, l := range splitLines(output) {
+		l = strings.TrimSpace(l)
+		// Aliases are shown as "name => command"
+		if i := strings.Index(l, " => "); i > 0 {
+			l = l[:i]
+		}
+		if commandListLine.MatchString(l) {
+			commands = append(commands, l)
+		}
+	}
+	if len(commands) == 0 {
+		return nil, ErrUnrecognised
+	}
+	return commands, nil
+}
@@ -0,0 +1,5 @@
+// rcon provides parsers for the output of common console commands, as returned by admin.Session.RconSync().
+// The console shows some things that the admin protocol doesn't, e.g the IP addresses of spectators.
+// Parsers take the Output of each Rcon line; lines containing line breaks are split up, and lines that aren't
+// recognised are skipped, as the exact output differs between OpenTTD versions.
+package rcon
@@ -0,0 +1,22 @@
+package rcon
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnrecognised is returned when the output doesn't contain what the parser was looking for.
+var ErrUnrecognised = errors.New("unrecognised console output")
+
+// splitLines splits up output lines containing line breaks, and drops trailing whitespace and empty lines.
+func splitLines(output []string) (lines []string) {
+	for _, o := range output {
+		for _, l := range strings.Split(o, "\n") {
+			l = strings.TrimRight(l, " \t\r")
+			if l != "" {
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,75 @@
+package rcon
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// parsers maps testdata directories to the parser for that command's output.
+var parsers = map[string]func([]string) (interface{}, error){
+	"clients":       func(o []string) (interface{}, error) { return ParseClients(o) },
+	"companies":     func(o []string) (interface{}, error) { return ParseCompanies(o) },
+	"server_info":   func(o []string) (interface{}, error) { return ParseServerInfo(o) },
+	"banlist":       func(o []string) (interface{}, error) { return ParseBanList(o) },
+	"get_date":      func(o []string) (interface{}, error) { return ParseDate(o) },
+	"content_state": func(o []string) (interface{}, error) { return ParseContentState(o) },
+	"list_ai":       func(o []string) (interface{}, error) { return ParseAIList(o) },
+	"version":       func(o []string) (interface{}, error) { return ParseVersion(o) },
+	"setting":       func(o []string) (interface{}, error) { return ParseSetting(o) },
+	"list_settings": func(o []string) (interface{}, error) { return ParseSettingList(o) },
+	"list_cmds":     func(o []string) (interface{}, error) { return ParseCommandList(o) },
+	"save":          func(o []string) (interface{}, error) { return ParseSaveResult(o) },
+	"ls":            func(o []string) (interface{}, error) { return ParseFileList(o) },
+}
+
+// TestGolden parses testdata/<command>/<version>.txt, and compares the result to <version>.golden.
+// Run with -update to rewrite the golden files.
+func TestGolden(t *testing.T) {
+	for command, parse := range parsers {
+		inputs, err := filepath.Glob(filepath.Join("testdata", command, "*.txt"))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, inputs, command)
+
+		for _, input := range inputs {
+			data, err := ioutil.ReadFile(input)
+			assert.NoError(t, err)
+
+			res, err := parse(strings.Split(string(data), "\n"))
+			assert.NoError(t, err, input)
+			got, err := json.MarshalIndent(res, "", "  ")
+			assert.NoError(t, err)
+
+			golden := strings.TrimSuffix(input, ".txt") + ".golden"
+			if *update {
+				assert.NoError(t, ioutil.WriteFile(golden, append(got, '\n'), 0644))
+				continue
+			}
+			want, err := ioutil.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(want), string(got), input)
+		}
+	}
+}
+
+func TestUnrecognised(t *testing.T) {
+	_, err := ParseDate([]string{"Command 'get_date' not found"})
+	assert.Equal(t, ErrUnrecognised, err)
+	_, err = ParseServerInfo(nil)
+	assert.Equal(t, ErrUnrecognised, err)
+	_, err = ParseAIList([]string{"Command 'list_ai' not found"})
+	assert.Equal(t, ErrUnrecognised, err)
+	_, err = ParseSaveResult([]string{"Saving map...", "Saving map failed"})
+	assert.Equal(t, ErrSaveFailed, err)
+
+	// Output lines can contain several lines of text
+	ais, err := ParseAIList([]string{"List of AIs:\n   SimpleAI (v4): Simple.\n   NoCAB (v419): Not Another AI.\n"})
+	assert.NoError(t, err)
+	assert.Len(t, ais, 2)
+}
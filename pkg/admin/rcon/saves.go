@@ -0,0 +1,66 @@
+package rcon
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrSaveFailed is returned by ParseSaveResult when the server couldn't save the game.
+var ErrSaveFailed = errors.New("saving map failed")
+
+var savedLine = regexp.MustCompile(`^Map successfully saved(?: to '(.*)')?\.?$`)
+
+// ParseSaveResult parses the output of the "save" command, returning the file the game was saved to (if the
+// server said). ErrSaveFailed is returned if the save failed, and ErrUnrecognised if the output says neither.
+func ParseSaveResult(output []string) (path string, err error) {
+	for _, l := range splitLines(output) {
+		l = strings.TrimSpace(l)
+		if m := savedLine.FindStringSubmatch(l); m != nil {
+			return m[1], nil
+		}
+		if strings.HasPrefix(l, "Saving map failed") {
+			return "", ErrSaveFailed
+		}
+	}
+	return "", ErrUnrecognised
+}
+
+// A File is an entry in the output of the "ls" command, which lists the savegames in the console's current
+// directory.
+type File struct {
+	// Index is the position of the entry in the list, which can be passed to "load" or "rm" instead of the name.
+	Index int `json:"index"`
+	// Name is the name of the savegame (without its extension) or directory.
+	Name string `json:"name"`
+	// Dir is set if the entry is a directory, or the parent directory ("..").
+	Dir bool `json:"dir"`
+}
+
+var fileLine = regexp.MustCompile(`^(\d+)\) (.+)$`)
+
+// ParseFileList parses the output of the "ls" (or "dir") command.
+func ParseFileList(output []string) (files []File, err error) {
+	for _, l := range splitLines(output) {
+		m := fileLine.FindStringSubmatch(strings.TrimSpace(l))
+		if m == nil {
+			continue
+		}
+		i, _ := strconv.Atoi(m[1])
+		f := File{Index: i, Name: m[2]}
+		for _, suffix := range []string{" (Directory)", " (Parent directory)"} {
+			if strings.HasSuffix(f.Name, suffix) {
+				f.Name = strings.TrimSuffix(f.Name, suffix)
+				f.Dir = true
+			}
+		}
+		if f.Dir {
+			f.Name = strings.TrimRight(f.Name, `/\`)
+		} else {
+			f.Name = strings.TrimSuffix(f.Name, ".sav")
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
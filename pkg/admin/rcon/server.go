@@ -0,0 +1,163 @@
+package rcon
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerInfo is the output of the "server_info" command.
+type ServerInfo struct {
+	Clients      int `json:"clients"`
+	MaxClients   int `json:"max_clients"`
+	Companies    int `json:"companies"`
+	MaxCompanies int `json:"max_companies"`
+	Spectators   int `json:"spectators"`
+	// MaxSpectators is only shown by servers before OpenTTD 12, and is 0 otherwise.
+	MaxSpectators int `json:"max_spectators"`
+	// InviteCode is only shown by servers from OpenTTD 12 onwards that are advertised.
+	InviteCode string `json:"invite_code,omitempty"`
+}
+
+var serverInfoLine = regexp.MustCompile(`^(Current/maximum|Current) (\w+):\s+(\d+)(?:\s*/\s*(\d+))?$`)
+
+// ParseServerInfo parses the output of the "server_info" command.
+func ParseServerInfo(output []string) (info ServerInfo, err error) {
+	found := false
+	for _, l := range splitLines(output) {
+		if strings.HasPrefix(l, "Invite code:") {
+			info.InviteCode = strings.TrimSpace(strings.TrimPrefix(l, "Invite code:"))
+			continue
+		}
+		m := serverInfoLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		current, _ := strconv.Atoi(m[3])
+		max, _ := strconv.Atoi(m[4])
+		switch m[2] {
+		case "clients":
+			info.Clients, info.MaxClients = current, max
+		case "companies":
+			info.Companies, info.MaxCompanies = current, max
+		case "spectators":
+			info.Spectators, info.MaxSpectators = current, max
+		default:
+			continue
+		}
+		found = true
+	}
+	if !found {
+		return info, ErrUnrecognised
+	}
+	return info, nil
+}
+
+var dateLine = regexp.MustCompile(`^Date: (\d+)-(\d+)-(\d+)$`)
+
+// ParseDate parses the output of the "get_date" command, returning the current game date.
+func ParseDate(output []string) (date time.Time, err error) {
+	for _, l := range splitLines(output) {
+		m := dateLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		a, _ := strconv.Atoi(m[1])
+		month, _ := strconv.Atoi(m[2])
+		c, _ := strconv.Atoi(m[3])
+		// Older servers show the date as day-month-year, newer ones as year-month-day
+		year, day := a, c
+		if len(m[1]) <= 2 {
+			year, day = c, a
+		}
+		return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+	}
+	return date, ErrUnrecognised
+}
+
+// Version is the output of the "version" command.
+type Version struct {
+	// Version is the version string of the server, e.g "14.1".
+	Version string `json:"version"`
+}
+
+var versionLine = regexp.MustCompile(`^(?:OpenTTD version:|Version:|OpenTTD)\s+(\S+)`)
+
+// ParseVersion parses the output of the "version" command.
+func ParseVersion(output []string) (v Version, err error) {
+	for _, l := range splitLines(output) {
+		if m := versionLine.FindStringSubmatch(strings.TrimSpace(l)); m != nil {
+			v.Version = m[1]
+			return v, nil
+		}
+	}
+	return v, ErrUnrecognised
+}
+
+// Content is a line of output from the "content state" command.
+type Content struct {
+	ID int `json:"id"`
+	// Type is the kind of content, e.g "NewGRF" or "AI".
+	Type string `json:"type"`
+	// State is whether the content is installed or selected for download, e.g "Installed" or "Not selected".
+	State    string `json:"state"`
+	Name     string `json:"name"`
+	UniqueID string `json:"unique_id"`
+	MD5      string `json:"md5"`
+}
+
+// ParseContentState parses the output of the "content state" command.
+func ParseContentState(output []string) (content []Content, err error) {
+	for _, l := range splitLines(output) {
+		// Names can contain commas, so take the fields on either side of it first
+		parts := strings.Split(l, ", ")
+		if len(parts) < 6 {
+			continue
+		}
+		id, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		n := len(parts)
+		content = append(content, Content{
+			ID:       id,
+			Type:     parts[1],
+			State:    parts[2],
+			Name:     strings.Join(parts[3:n-2], ", "),
+			UniqueID: parts[n-2],
+			MD5:      parts[n-1],
+		})
+	}
+	return content, nil
+}
+
+// An AI is an entry in the output of the "list_ai" command.
+type AI struct {
+	Name        string `json:"name"`
+	Version     int    `json:"version"`
+	Description string `json:"description"`
+}
+
+var aiLine = regexp.MustCompile(`^\s*(.+?) \(v(\d+)\): (.*)$`)
+
+// ParseAIList parses the output of the "list_ai" command (or "list_ai_libs").
+func ParseAIList(output []string) (ais []AI, err error) {
+	found := false
+	for _, l := range splitLines(output) {
+		if strings.HasPrefix(l, "List of AI") {
+			found = true
+			continue
+		}
+		m := aiLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		v, _ := strconv.Atoi(m[2])
+		ais = append(ais, AI{Name: m[1], Version: v, Description: m[3]})
+	}
+	if !found && len(ais) == 0 {
+		return nil, ErrUnrecognised
+	}
+	return ais, nil
+}
@@ -0,0 +1,116 @@
+package rcon
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A Setting is a server setting, as shown by the "setting" and "list_settings" commands.
+type Setting struct {
+	Name string `json:"name"`
+	// Value is a bool, int64 or string, depending on the kind of setting.
+	Value interface{} `json:"value"`
+	// Min and Max are the range of values the setting allows, if the command showed them.
+	// The "setting" command shows them for all but string settings; "list_settings" never does.
+	Min *int64 `json:"min,omitempty"`
+	Max *int64 `json:"max,omitempty"`
+}
+
+// String returns the value as the console shows it, which is also how it's given to the "setting" command.
+func (s Setting) String() string {
+	return FormatSettingValue(s.Value)
+}
+
+// ParseSettingValue converts a setting value as shown on the console to a bool, int64, or (failing both) string.
+func ParseSettingValue(value string) interface{} {
+	switch value {
+	case "on", "true":
+		return true
+	case "off", "false":
+		return false
+	}
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+	return value
+}
+
+// FormatSettingValue converts a setting value to how the console shows it.
+// As well as the types ParseSettingValue returns, it takes any other number type, as found when decoding JSON.
+func FormatSettingValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "on"
+		}
+		return "off"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// SettingValuesEqual returns whether two setting values are the same once given to the console, e.g "on" and true.
+func SettingValuesEqual(a, b interface{}) bool {
+	normalise := func(v interface{}) string {
+		return FormatSettingValue(ParseSettingValue(FormatSettingValue(v)))
+	}
+	return normalise(a) == normalise(b)
+}
+
+var settingLine = regexp.MustCompile(`^Current value for '(.+?)' is:? '(.*)'(?: \(min: (?:\(0\) )?(-?\d+), max: (-?\d+)\))?\.?$`)
+
+// ParseSetting parses the output of the "setting <name>" command.
+func ParseSetting(output []string) (setting Setting, err error) {
+	for _, l := range splitLines(output) {
+		m := settingLine.FindStringSubmatch(l)
+		if m == nil {
+			continue
+		}
+		setting.Name = m[1]
+		if m[3] == "" {
+			// Only string settings don't have a range
+			setting.Value = m[2]
+			return setting, nil
+		}
+		setting.Value = ParseSettingValue(m[2])
+		min, _ := strconv.ParseInt(m[3], 10, 64)
+		max, _ := strconv.ParseInt(m[4], 10, 64)
+		setting.Min, setting.Max = &min, &max
+		return setting, nil
+	}
+	return setting, ErrUnrecognised
+}
+
+var settingListLine = regexp.MustCompile(`^([a-z0-9_.]+) = (.*)$`)
+
+// ParseSettingList parses the output of the "list_settings" command.
+// The command doesn't say what kind each setting is, so string settings which look like numbers or bools are
+// returned as those.
+func ParseSettingList(output []string) (settings []Setting, err error) {
+	found := false
+	for _, l := range splitLines(output) {
+		if strings.HasPrefix(l, "All settings") {
+			found = true
+			continue
+		}
+		m := settingListLine.FindStringSubmatch(strings.TrimSpace(l))
+		if m == nil {
+			continue
+		}
+		settings = append(settings, Setting{Name: m[1], Value: ParseSettingValue(m[2])})
+	}
+	if !found && len(settings) == 0 {
+		return nil, ErrUnrecognised
+	}
+	return settings, nil
+}
@@ -0,0 +1,230 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Typed wrappers around common console commands, which check the console output to see whether they worked.
+
+// An RconError is returned when the console output of a command shows that it failed.
+// Use errors.Is to check which kind of failure it was, e.g ErrNoSuchClient.
+type RconError struct {
+	// Command is the console command that was run.
+	Command string
+	// Output is the console output of the command.
+	Output []Rcon
+	// Err is the kind of failure.
+	Err error
+}
+
+func (e *RconError) Error() string {
+	for _, colour := range []enum.TextColour{enum.ConsoleColourError, enum.ConsoleColourWarning} {
+		for _, l := range e.Output {
+			if l.Colour.Base() == colour {
+				return fmt.Sprintf("%s: %s", e.Err, l.Output)
+			}
+		}
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the kind of failure.
+func (e *RconError) Unwrap() error {
+	return e.Err
+}
+
+// rconFailures maps (lower case) snippets of console output to the failures they mean, most specific first.
+// Snippets only match lines in the colour OpenTTD prints them in: mostly CC_ERROR, but the AI commands print their
+// failures as warnings, and a few commands report failure in the default colour.
+var rconFailures = []struct {
+	match  string
+	colour enum.TextColour
+	err    error
+}{
+	{"unknown setting", enum.ConsoleColourError, ErrUnknownSetting},
+	{"is not an integer", enum.ConsoleColourError, ErrInvalidSettingValue},
+	{"not available during network games", enum.ConsoleColourError, ErrNetworkSetting},
+	{"not found", enum.ConsoleColourError, ErrUnknownCommand},
+	{"silly boy", enum.ConsoleColourError, ErrNotAllowed},
+	{"invalid client", enum.ConsoleColourError, ErrNoSuchClient},
+	{"invalid company", enum.ConsoleColourError, ErrNoSuchCompany},
+	{"company does not exist", enum.ConsoleColourError, ErrNoSuchCompany},
+	{"client is connected to that company", enum.ConsoleColourError, ErrCompanyInUse},
+	{"ai compan", enum.ConsoleColourError, ErrAICompany},
+	{"no such file", enum.ConsoleColourError, ErrNoSuchSave},
+	{"unknown company", enum.ConsoleColourDefault, ErrNoSuchCompany},
+	{"not controlled by an ai", enum.ConsoleColourWarning, ErrHumanCompany},
+	{"no more free slots", enum.ConsoleColourWarning, ErrNoFreeCompanySlot},
+	{"failed to load the specified ai", enum.ConsoleColourWarning, ErrUnknownAI},
+	{"ais are not allowed", enum.ConsoleColourWarning, ErrNotAllowed},
+	{"already paused", enum.ConsoleColourDefault, ErrAlreadyPaused},
+	{"already unpaused", enum.ConsoleColourDefault, ErrNotPaused},
+	{"invalid list index", enum.ConsoleColourDefault, ErrNotBanned},
+}
+
+// checkRconOutput returns an RconError if the console output shows the command failed, i.e it matches one of
+// rconFailures, or has any other CC_ERROR lines (which are ErrCommandFailed).
+// Other lines are never taken as failures, whatever they say, as they can echo names and values from the game.
+func checkRconOutput(command string, output []Rcon) error {
+	for _, l := range output {
+		text := strings.ToLower(l.Output)
+		for _, f := range rconFailures {
+			if l.Colour.Base() == f.colour && strings.Contains(text, f.match) {
+				return &RconError{Command: command, Output: output, Err: f.err}
+			}
+		}
+	}
+	for _, l := range output {
+		if l.Colour.Base() == enum.ConsoleColourError {
+			return &RconError{Command: command, Output: output, Err: ErrCommandFailed}
+		}
+	}
+	return nil
+}
+
+// quoteConsoleArg quotes the argument so the console treats it as a single argument.
+func quoteConsoleArg(arg string) string {
+	// The console has no way of escaping new lines, and would take them as the end of the command
+	arg = strings.NewReplacer("\r", " ", "\n", " ").Replace(arg)
+	arg = strings.Replace(arg, `"`, `\"`, -1)
+	// A trailing backslash would escape the closing quote, so keep them apart
+	if strings.HasSuffix(arg, `\`) {
+		arg += " "
+	}
+	return `"` + arg + `"`
+}
+
+// consoleCommand runs the console command built from the given arguments, and checks whether it failed.
+// Arguments are quoted if they need to be.
+func (s *Session) consoleCommand(args ...string) (output []Rcon, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.rconTimeout())
+	defer cancel()
+
+	output, err = s.consoleCommandContext(ctx, args...)
+	if err == context.DeadlineExceeded {
+		err = ErrRconTimeout
+	}
+	return output, err
+}
+
+// consoleLine builds a console command from the given arguments, quoting them if they need to be.
+func consoleLine(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if i == 0 || (arg != "" && !strings.ContainsAny(arg, " \t\"\r\n")) {
+			quoted[i] = arg
+		} else {
+			quoted[i] = quoteConsoleArg(arg)
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// consoleCommandContext is consoleCommand, giving up once ctx is done.
+func (s *Session) consoleCommandContext(ctx context.Context, args ...string) (output []Rcon, err error) {
+	command := consoleLine(args...)
+	output, err = s.RconContext(ctx, command)
+	if err != nil {
+		return output, err
+	}
+	return output, checkRconOutput(command, output)
+}
+
+// consoleCompany returns the company ID as the console expects it, i.e as the company number players see.
+func consoleCompany(id enum.CompanyID) string {
+	if id.IsSpectator() {
+		return "255"
+	}
+	return strconv.Itoa(int(id.Number()))
+}
+
+// Kick disconnects the given client from the server, with an optional reason shown to them.
+func (s *Session) Kick(id enum.ClientID, reason string) (err error) {
+	args := []string{"kick", strconv.Itoa(int(id))}
+	if reason != "" {
+		args = append(args, reason)
+	}
+	_, err = s.consoleCommand(args...)
+	return err
+}
+
+// Ban disconnects the given client from the server, and bans their IP address from rejoining.
+func (s *Session) Ban(id enum.ClientID, reason string) (err error) {
+	args := []string{"ban", strconv.Itoa(int(id))}
+	if reason != "" {
+		args = append(args, reason)
+	}
+	_, err = s.consoleCommand(args...)
+	return err
+}
+
+// BanIP bans the given IP address from the server, disconnecting anyone currently connected from it.
+// It's not an error if nobody is connected from it.
+func (s *Session) BanIP(ip net.IP, reason string) (err error) {
+	args := []string{"ban", ip.String()}
+	if reason != "" {
+		args = append(args, reason)
+	}
+	_, err = s.consoleCommand(args...)
+	return err
+}
+
+// Unban removes the given IP address from the ban list.
+func (s *Session) Unban(ip net.IP) (err error) {
+	_, err = s.consoleCommand("unban", ip.String())
+	return err
+}
+
+// Pause pauses the game.
+// ErrAlreadyPaused is returned if it's already paused.
+func (s *Session) Pause() (err error) {
+	_, err = s.consoleCommand("pause")
+	return err
+}
+
+// Unpause unpauses the game.
+// ErrNotPaused is returned if it isn't paused.
+func (s *Session) Unpause() (err error) {
+	_, err = s.consoleCommand("unpause")
+	return err
+}
+
+// ResetCompany removes the given company from the game.
+// The company can't have any clients in it - move them out with MoveClient first.
+func (s *Session) ResetCompany(id enum.CompanyID) (err error) {
+	if !id.IsValid() {
+		return &RconError{Command: "reset_company", Err: ErrNoSuchCompany}
+	}
+	_, err = s.consoleCommand("reset_company", consoleCompany(id))
+	return err
+}
+
+// MoveClient moves the given client to the given company, or to spectators if it's enum.CompanyIDSpectator.
+func (s *Session) MoveClient(id enum.ClientID, company enum.CompanyID) (err error) {
+	if !company.IsValid() && !company.IsSpectator() {
+		return &RconError{Command: "move", Err: ErrNoSuchCompany}
+	}
+	_, err = s.consoleCommand("move", strconv.Itoa(int(id)), consoleCompany(company))
+	return err
+}
+
+// Say broadcasts a chat message to everyone on the server, from the server.
+// Unlike Broadcast, this goes through the console, so it shows up in the server's own logs as it would if typed there.
+func (s *Session) Say(message string) (err error) {
+	_, err = s.consoleCommand("say", message)
+	return err
+}
+
+// rconLines returns the text of each line of console output.
+func rconLines(output []Rcon) []string {
+	lines := make([]string, len(output))
+	for i, l := range output {
+		lines[i] = l.Output
+	}
+	return lines
+}
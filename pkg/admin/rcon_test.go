@@ -0,0 +1,210 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestConnection connects the session to a local listener which discards whatever it's sent.
+// It returns the listening channel, which the caller should close when done.
+func newTestConnection(t *testing.T, s *Session) chan interface{} {
+	l, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.NoError(t, err)
+	go func() {
+		conn, err := l.Accept()
+		l.Close()
+		if err == nil {
+			io.Copy(ioutil.Discard, conn)
+		}
+	}()
+
+	s.conn, err = net.DialTCP("tcp", nil, l.Addr().(*net.TCPAddr))
+	assert.NoError(t, err)
+	listening := make(chan interface{})
+	s.listening = listening
+	go s.handleRconRequests(listening)
+	return listening
+}
+
+// waitForRcon waits until the server is running the given command for us.
+func waitForRcon(s *Session, command string) {
+	for {
+		s.rconMu.Lock()
+		current := s.rconCurrent
+		s.rconMu.Unlock()
+		if current != nil && current.Command == command && !current.finished() {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRconContext(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer s.conn.Close()
+
+	// Output nobody asked for must not block
+	s.onInterface(&Rcon{Output: "unsolicited"})
+
+	type result struct {
+		output []Rcon
+		err    error
+	}
+	results := make(chan result)
+	run := func(ctx context.Context, command string) {
+		output, err := s.RconContext(ctx, command)
+		results <- result{output, err}
+	}
+
+	go run(context.Background(), "clients")
+	waitForRcon(s, "clients")
+	s.onInterface(&Rcon{Colour: 1, Output: "Client #1"})
+	s.onInterface(&RconEnd{Command: "something else"})
+	s.onInterface(&Rcon{Colour: 1, Output: "Client #2"})
+	s.onInterface(&RconEnd{Command: "clients"})
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, []Rcon{{Colour: 1, Output: "Client #1"}, {Colour: 1, Output: "Client #2"}}, res.output)
+
+	// A caller giving up doesn't let the late output leak into the next command
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	go run(ctx, "slow")
+	res = <-results
+	assert.Equal(t, context.DeadlineExceeded, res.err)
+	go run(context.Background(), "fast")
+	s.onInterface(&Rcon{Output: "slow output"})
+	s.onInterface(&RconEnd{Command: "slow"})
+	waitForRcon(s, "fast")
+	s.onInterface(&Rcon{Output: "fast output"})
+	s.onInterface(&RconEnd{Command: "fast"})
+	res = <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, []Rcon{{Output: "fast output"}}, res.output)
+
+	// Disconnecting fails the outstanding command
+	go run(context.Background(), "never")
+	waitForRcon(s, "never")
+	close(listening)
+	res = <-results
+	assert.Equal(t, ErrDisconnected, res.err)
+
+	// As do any after it
+	_, err := s.RconContext(context.Background(), "after")
+	assert.Equal(t, ErrDisconnected, err)
+}
+
+func TestRconDisconnected(t *testing.T) {
+	// Commands on a session that was never opened fail straight away, rather than waiting forever
+	s := newTestSession()
+	_, err := s.RconContext(context.Background(), "clients")
+	assert.Equal(t, ErrDisconnected, err)
+	_, err = s.RconSync("clients")
+	assert.Equal(t, ErrDisconnected, err)
+	assert.Equal(t, ErrDisconnected, s.Rcon("clients"))
+
+	out := s.RconStream(context.Background(), "clients")
+	for range out.Lines {
+	}
+	assert.Equal(t, ErrDisconnected, out.Err())
+}
+
+func TestRconStream(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	out := s.RconStream(context.Background(), "list_settings")
+	waitForRcon(s, "list_settings")
+
+	// Lines arrive as the server sends them
+	s.onInterface(&Rcon{Colour: 2, Output: "a = 1"})
+	assert.Equal(t, Rcon{Colour: 2, Output: "a = 1"}, <-out.Lines)
+	s.onInterface(&Rcon{Colour: 2, Output: "b = 2"})
+	s.onInterface(&Rcon{Colour: 2, Output: "c = 3"})
+	s.onInterface(&RconEnd{Command: "list_settings"})
+
+	var rest []string
+	for l := range out.Lines {
+		rest = append(rest, l.Output)
+	}
+	assert.Equal(t, []string{"b = 2", "c = 3"}, rest)
+	assert.NoError(t, out.Err())
+
+	// Cancelling closes the stream early
+	ctx, cancel := context.WithCancel(context.Background())
+	out = s.RconStream(ctx, "content state")
+	waitForRcon(s, "content state")
+	s.onInterface(&Rcon{Output: "lots"})
+	cancel()
+	for range out.Lines {
+	}
+	assert.Equal(t, context.Canceled, out.Err())
+	s.onInterface(&RconEnd{Command: "content state"})
+}
+
+func TestRconCommands(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	errs := make(chan error)
+
+	// Arguments are quoted as required, and failures are recognised
+	go func() { errs <- s.Kick(5, `say "sorry"`) }()
+	waitForRcon(s, `kick 5 "say \"sorry\""`)
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourError, Output: "Invalid client"})
+	s.onInterface(&RconEnd{Command: `kick 5 "say \"sorry\""`})
+	err := <-errs
+	assert.True(t, errors.Is(err, ErrNoSuchClient))
+	assert.Equal(t, "no such client: Invalid client", err.Error())
+
+	// Companies are numbered from 1 on the console
+	go func() { errs <- s.MoveClient(5, 2) }()
+	waitForRcon(s, "move 5 3")
+	s.onInterface(&RconEnd{Command: "move 5 3"})
+	assert.NoError(t, <-errs)
+
+	go func() { errs <- s.MoveClient(5, enum.CompanyIDSpectator) }()
+	waitForRcon(s, "move 5 255")
+	s.onInterface(&RconEnd{Command: "move 5 255"})
+	assert.NoError(t, <-errs)
+
+	go func() { errs <- s.Pause() }()
+	waitForRcon(s, "pause")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourDefault, Output: "Game is already paused."})
+	s.onInterface(&RconEnd{Command: "pause"})
+	assert.True(t, errors.Is(<-errs, ErrAlreadyPaused))
+
+	assert.True(t, errors.Is(s.ResetCompany(enum.CompanyIDSpectator), ErrNoSuchCompany))
+
+	// Banning an address nobody is connected from still bans it
+	go func() { errs <- s.BanIP(net.IPv4(10, 0, 0, 1), "") }()
+	waitForRcon(s, "ban 10.0.0.1")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourDefault, Output: "Client not online, address added to banlist."})
+	s.onInterface(&RconEnd{Command: "ban 10.0.0.1"})
+	assert.NoError(t, <-errs)
+
+	// The closing quote mustn't be escaped by the argument itself
+	assert.Equal(t, `say a\`, consoleLine("say", `a\`))
+	assert.Equal(t, `say "a b\ "`, consoleLine("say", `a b\`))
+	assert.Equal(t, `say "\"a\""`, consoleLine("say", `"a"`))
+
+	// Only lines in the colour of the failure count
+	assert.NoError(t, checkRconOutput("x", []Rcon{{Colour: enum.ConsoleColourDefault, Output: "Client #3 'failed to connect' joined"}}))
+	assert.True(t, errors.Is(checkRconOutput("x", []Rcon{{Colour: enum.ConsoleColourWarning, Output: "Company is not controlled by an AI."}}), ErrHumanCompany))
+
+	// Unrecognised errors still count as failures
+	assert.True(t, errors.Is(checkRconOutput("x", []Rcon{{Colour: enum.ConsoleColourError, Output: "Oops"}}), ErrCommandFailed))
+	assert.NoError(t, checkRconOutput("x", []Rcon{{Output: "Map successfully saved to 'x.sav'."}}))
+}
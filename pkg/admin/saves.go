@@ -0,0 +1,175 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/rcon"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Saving the game, and keeping rotating backups of it.
+
+// Defaults for the Session's Backup fields.
+const (
+	DefaultBackupPrefix = "backup_"
+	DefaultBackupLayout = "20060102-150405"
+	DefaultBackupKeep   = 10
+)
+
+// Save saves the game on the server, under the given file name.
+// See SaveContext for how the save is confirmed.
+func (s *Session) Save(name string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.rconTimeout())
+	defer cancel()
+
+	_, err = s.SaveContext(ctx, name)
+	if err == context.DeadlineExceeded {
+		err = ErrRconTimeout
+	}
+	return err
+}
+
+// SaveContext saves the game on the server, under the given file name, and returns the file it was saved to if the
+// server said.
+// The save is only successful once the server confirms it. This is normally in the output of the command, but the
+// server can also report it on the console, which we watch for if subscribed to UpdateTypeConsole. If ctx is done
+// before either, ErrSaveUnconfirmed is returned. If the save failed, the error is an RconError wrapping
+// rcon.ErrSaveFailed.
+func (s *Session) SaveContext(ctx context.Context, name string) (path string, err error) {
+	type result struct {
+		path string
+		err  error
+	}
+	results := make(chan result, 1)
+	remove := s.AddHandler(func(_ *Session, c *Console) {
+		path, err := rcon.ParseSaveResult([]string{c.Message})
+		if err == rcon.ErrUnrecognised {
+			return
+		}
+		select {
+		case results <- result{path, err}:
+		default:
+		}
+	})
+	defer remove()
+
+	command := consoleLine("save", name)
+	output, err := s.RconContext(ctx, command)
+	if err != nil {
+		return "", err
+	}
+	path, err = rcon.ParseSaveResult(rconLines(output))
+	if err == rcon.ErrUnrecognised {
+		if err = checkRconOutput(command, output); err != nil {
+			return "", err
+		}
+		select {
+		case r := <-results:
+			path, err = r.path, r.err
+		case <-ctx.Done():
+			return "", ErrSaveUnconfirmed
+		}
+	}
+	if err != nil {
+		return "", &RconError{Command: command, Output: output, Err: err}
+	}
+	return path, nil
+}
+
+// ListSaves returns the savegames (and directories) in the server's console's current directory, as shown by "ls".
+func (s *Session) ListSaves(ctx context.Context) (files []rcon.File, err error) {
+	err = s.consoleQuery(ctx, func(output []string) (err error) {
+		files, err = rcon.ParseFileList(output)
+		return err
+	}, "ls")
+	return files, err
+}
+
+// DeleteSave deletes the named savegame from the server's console's current directory.
+// ErrNoSuchSave is returned if there's no such savegame.
+func (s *Session) DeleteSave(ctx context.Context, name string) (err error) {
+	_, err = s.consoleCommandContext(ctx, "rm", name)
+	// Newer servers say "'<name>' could not be found." rather than "No such file or directory"
+	var rerr *RconError
+	if errors.As(err, &rerr) && rerr.Err == ErrCommandFailed {
+		for _, l := range rerr.Output {
+			if l.Colour.Base() == enum.ConsoleColourError && strings.Contains(l.Output, "could not be found") {
+				rerr.Err = ErrNoSuchSave
+			}
+		}
+	}
+	return err
+}
+
+// backupOptions returns the Session's backup settings, with the defaults filled in.
+func (s *Session) backupOptions() (prefix, layout string, keep int) {
+	prefix, layout, keep = s.BackupPrefix, s.BackupLayout, s.BackupKeep
+	if prefix == "" {
+		prefix = DefaultBackupPrefix
+	}
+	if layout == "" {
+		layout = DefaultBackupLayout
+	}
+	if keep == 0 {
+		keep = DefaultBackupKeep
+	}
+	return prefix, layout, keep
+}
+
+// Backups returns the names of the backups taken with Backup that are on the server, oldest first.
+// Like ListSaves, this lists the console's current directory, which is the save directory unless it has been
+// changed with "cd".
+func (s *Session) Backups(ctx context.Context) (names []string, err error) {
+	files, err := s.ListSaves(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix, layout, _ := s.backupOptions()
+	for _, f := range files {
+		if f.Dir || !strings.HasPrefix(f.Name, prefix) {
+			continue
+		}
+		if _, err := time.Parse(layout, strings.TrimPrefix(f.Name, prefix)); err == nil {
+			names = append(names, f.Name)
+		}
+	}
+	// The layout might not sort in time order, so sort by time
+	sort.Slice(names, func(i, j int) bool {
+		a, _ := time.Parse(layout, strings.TrimPrefix(names[i], prefix))
+		b, _ := time.Parse(layout, strings.TrimPrefix(names[j], prefix))
+		return a.Before(b)
+	})
+	return names, nil
+}
+
+// Backup saves the game under a new name (see BackupPrefix and BackupLayout), waits for the server to confirm
+// the save, and then deletes the oldest backups, keeping the latest BackupKeep.
+// Old backups are looked for in the console's current directory (see Backups), so don't leave the console in
+// another directory, or they won't be found.
+// The name of the new backup is returned even if deleting the old ones fails, as the backup itself was taken.
+func (s *Session) Backup(ctx context.Context) (name string, err error) {
+	prefix, layout, keep := s.backupOptions()
+	name = prefix + time.Now().UTC().Format(layout)
+	if _, err = s.SaveContext(ctx, name); err != nil {
+		return "", err
+	}
+	if keep < 0 {
+		return name, nil
+	}
+
+	backups, err := s.Backups(ctx)
+	if err != nil {
+		return name, fmt.Errorf("listing old backups: %w", err)
+	}
+	for len(backups) > keep {
+		if err = s.DeleteSave(ctx, backups[0]); err != nil {
+			return name, fmt.Errorf("deleting old backup %s: %w", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+	return name, nil
+}
@@ -0,0 +1,139 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/rcon"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveContext(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	type result struct {
+		path string
+		err  error
+	}
+	results := make(chan result)
+	save := func(ctx context.Context) {
+		path, err := s.SaveContext(ctx, "my game")
+		results <- result{path, err}
+	}
+
+	go save(context.Background())
+	waitForRcon(s, `save "my game"`)
+	s.onInterface(&Rcon{Output: "Saving map..."})
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourInfo, Output: "Map successfully saved to 'my game.sav'."})
+	s.onInterface(&RconEnd{Command: `save "my game"`})
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, "my game.sav", res.path)
+
+	go save(context.Background())
+	waitForRcon(s, `save "my game"`)
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourError, Output: "Saving map failed"})
+	s.onInterface(&RconEnd{Command: `save "my game"`})
+	res = <-results
+	assert.True(t, errors.Is(res.err, rcon.ErrSaveFailed))
+
+	// The confirmation can also turn up on the console
+	go save(context.Background())
+	waitForRcon(s, `save "my game"`)
+	s.onInterface(&Rcon{Output: "Saving map..."})
+	s.onInterface(&RconEnd{Command: `save "my game"`})
+	s.handleEvent(consoleEventType, &Console{Origin: "console", Message: "Map successfully saved to 'my game.sav'."})
+	res = <-results
+	assert.NoError(t, res.err)
+
+	// Or not at all
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	go save(ctx)
+	waitForRcon(s, `save "my game"`)
+	s.onInterface(&RconEnd{Command: `save "my game"`})
+	res = <-results
+	assert.Equal(t, ErrSaveUnconfirmed, res.err)
+}
+
+func TestDeleteSave(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	errs := make(chan error)
+	for _, output := range []string{
+		// As worded by older servers, and newer ones
+		"my game: No such file or directory.",
+		"'my game' could not be found.",
+	} {
+		go func() { errs <- s.DeleteSave(context.Background(), "my game") }()
+		waitForRcon(s, `rm "my game"`)
+		s.onInterface(&Rcon{Colour: enum.ConsoleColourError, Output: output})
+		s.onInterface(&RconEnd{Command: `rm "my game"`})
+		assert.True(t, errors.Is(<-errs, ErrNoSuchSave), output)
+	}
+
+	go func() { errs <- s.DeleteSave(context.Background(), "my game") }()
+	waitForRcon(s, `rm "my game"`)
+	s.onInterface(&RconEnd{Command: `rm "my game"`})
+	assert.NoError(t, <-errs)
+}
+
+func TestBackup(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+	s.BackupKeep = 2
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+	go func() {
+		name, err := s.Backup(context.Background())
+		results <- result{name, err}
+	}()
+
+	// respond answers the next command, which should start with the given text, with the given output
+	respond := func(prefix string, output ...string) string {
+		for {
+			s.rconMu.Lock()
+			current := s.rconCurrent
+			s.rconMu.Unlock()
+			if current != nil && !current.finished() && strings.HasPrefix(current.Command, prefix) {
+				for _, l := range output {
+					s.onInterface(&Rcon{Output: l})
+				}
+				s.onInterface(&RconEnd{Command: current.Command})
+				return current.Command
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	save := respond("save backup_", "Map successfully saved")
+	name := strings.TrimPrefix(save, "save ")
+	respond("ls",
+		"0) .. (Parent directory)",
+		"1) autosave/ (Directory)",
+		"2) backup_20260101-000000",
+		"3) "+name,
+		"4) backup_not-a-backup",
+		"5) backup_20251231-235959",
+		"6) my game")
+	// The oldest backups go first
+	assert.Equal(t, "rm backup_20251231-235959", respond("rm "))
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, name, res.name)
+}
@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"io"
+	"os"
+	"strings"
+)
+
+// Running files of console commands, for scripted maintenance.
+
+// ScriptOptions control how RunScript runs a script.
+type ScriptOptions struct {
+	// Vars are substituted for $name or ${name} in the script. $$ is a literal $.
+	Vars map[string]string
+	// ContinueOnError keeps running the script after a command fails, rather than stopping there.
+	ContinueOnError bool
+	// Check returns an error if the output of a command shows that it failed. By default, commands fail if they
+	// print any errors (i.e CC_ERROR lines).
+	Check func(command string, output []Rcon) error
+}
+
+// checkScriptOutput is the default ScriptOptions.Check. Unlike the typed commands, there's no telling what a
+// command's output means, so it only fails commands that print errors - which are then classified as for the typed
+// commands, e.g ErrNoSuchCompany.
+func checkScriptOutput(command string, output []Rcon) error {
+	var failures []Rcon
+	for _, l := range output {
+		if l.Colour.Base() == enum.ConsoleColourError {
+			failures = append(failures, l)
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	// Errors are always failures, so this is always an RconError
+	err := checkRconOutput(command, failures).(*RconError)
+	err.Output = output
+	return err
+}
+
+// A ScriptStep is a command from a script, and what happened when it was run.
+type ScriptStep struct {
+	// Line is the line number of the command in the script.
+	Line int `json:"line"`
+	// Command is the console command, after variables have been substituted.
+	Command string `json:"command"`
+	Output  []Rcon `json:"output"`
+	// Err is why the command failed, if it did.
+	Err error `json:"-"`
+}
+
+// A Transcript is the record of a script being run.
+type Transcript []ScriptStep
+
+// String returns the transcript as it would look on the console: each command, followed by its output.
+func (t Transcript) String() string {
+	var b strings.Builder
+	for _, step := range t {
+		fmt.Fprintf(&b, "> %s\n", step.Command)
+		for _, l := range step.Output {
+			b.WriteString(l.Output)
+			b.WriteByte('\n')
+		}
+		if step.Err != nil {
+			fmt.Fprintf(&b, "! line %d: %s\n", step.Line, step.Err)
+		}
+	}
+	return b.String()
+}
+
+// Failed returns the steps which failed.
+func (t Transcript) Failed() (failed []ScriptStep) {
+	for _, step := range t {
+		if step.Err != nil {
+			failed = append(failed, step)
+		}
+	}
+	return failed
+}
+
+// A ScriptError is returned by RunScript when a command fails, or the script can't be read.
+type ScriptError struct {
+	Line int
+	Err  error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("script line %d: %s", e.Line, e.Err)
+}
+
+// Unwrap returns why the line failed.
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// parseScript reads the commands from a script, substituting variables.
+// Blank lines, and comment lines starting with # or //, are skipped.
+func parseScript(script io.Reader, vars map[string]string) (steps Transcript, err error) {
+	scanner := bufio.NewScanner(script)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "//") {
+			continue
+		}
+
+		var undefined []string
+		command := os.Expand(text, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			value, ok := vars[name]
+			if !ok {
+				undefined = append(undefined, name)
+			}
+			return value
+		})
+		if len(undefined) > 0 {
+			return nil, &ScriptError{Line: line, Err: fmt.Errorf("%w: %s", ErrUndefinedVariable, strings.Join(undefined, ", "))}
+		}
+		steps = append(steps, ScriptStep{Line: line, Command: command})
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, &ScriptError{Line: line + 1, Err: err}
+	}
+	return steps, nil
+}
+
+// RunScript runs each console command in the script in turn using RconSync, and returns a transcript of them.
+// The whole script is read before anything is run, so a script with undefined variables doesn't run at all.
+// If a command fails (see ScriptOptions.Check), a ScriptError is returned, and unless opts.ContinueOnError is set,
+// the script stops there. The transcript only includes the commands that were run.
+func RunScript(s *Session, script io.Reader, opts ScriptOptions) (transcript Transcript, err error) {
+	steps, err := parseScript(script, opts.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	check := opts.Check
+	if check == nil {
+		check = checkScriptOutput
+	}
+	for _, step := range steps {
+		step.Output, step.Err = s.RconSync(step.Command)
+		answered := step.Err == nil
+		if answered {
+			step.Err = check(step.Command, step.Output)
+		}
+		transcript = append(transcript, step)
+		if step.Err == nil {
+			continue
+		}
+		if err == nil {
+			err = &ScriptError{Line: step.Line, Err: step.Err}
+		}
+		// There's no point carrying on if the server isn't answering
+		if !answered || !opts.ContinueOnError {
+			break
+		}
+	}
+	return transcript, err
+}
@@ -0,0 +1,86 @@
+package admin
+
+import (
+	"errors"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `# Get ready for the event
+setting network.server_name "${event} at $$5 entry"
+
+// Out with the old
+reset_company $company
+`
+	steps, err := parseScript(strings.NewReader(script), map[string]string{"event": "Race", "company": "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, Transcript{
+		{Line: 2, Command: `setting network.server_name "Race at $5 entry"`},
+		{Line: 5, Command: "reset_company 2"},
+	}, steps)
+
+	_, err = parseScript(strings.NewReader(script), map[string]string{"event": "Race"})
+	assert.True(t, errors.Is(err, ErrUndefinedVariable))
+	assert.Equal(t, "script line 5: undefined variable: company", err.Error())
+}
+
+func TestRunScript(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	type result struct {
+		transcript Transcript
+		err        error
+	}
+	results := make(chan result)
+	run := func(opts ScriptOptions) {
+		transcript, err := RunScript(s, strings.NewReader("pause\nreset_company 1\nunpause\n"), opts)
+		results <- result{transcript, err}
+	}
+
+	// The script stops at the first failure
+	go run(ScriptOptions{})
+	waitForRcon(s, "pause")
+	s.onInterface(&RconEnd{Command: "pause"})
+	waitForRcon(s, "reset_company 1")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourError, Output: "Company does not exist. Company-id must be in range 1-15."})
+	s.onInterface(&RconEnd{Command: "reset_company 1"})
+	res := <-results
+	assert.True(t, errors.Is(res.err, ErrNoSuchCompany))
+	assert.Len(t, res.transcript, 2)
+	assert.Len(t, res.transcript.Failed(), 1)
+	assert.Equal(t, "> pause\n> reset_company 1\nCompany does not exist. Company-id must be in range 1-15.\n"+
+		"! line 2: no such company: Company does not exist. Company-id must be in range 1-15.\n", res.transcript.String())
+
+	// Unless asked to carry on. Output that doesn't show an error isn't a failure, whatever it says.
+	go run(ScriptOptions{ContinueOnError: true})
+	waitForRcon(s, "pause")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourDefault, Output: "Game is already paused."})
+	s.onInterface(&RconEnd{Command: "pause"})
+	waitForRcon(s, "reset_company 1")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourError, Output: "Company does not exist. Company-id must be in range 1-15."})
+	s.onInterface(&RconEnd{Command: "reset_company 1"})
+	waitForRcon(s, "unpause")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourDefault, Output: "Unpausing, though the last save failed"})
+	s.onInterface(&RconEnd{Command: "unpause"})
+	res = <-results
+	assert.True(t, errors.Is(res.err, ErrNoSuchCompany))
+	assert.Len(t, res.transcript, 3)
+	if assert.Len(t, res.transcript.Failed(), 1) {
+		assert.Equal(t, 2, res.transcript.Failed()[0].Line)
+	}
+
+	// Callers can decide for themselves
+	go run(ScriptOptions{Check: checkRconOutput})
+	waitForRcon(s, "pause")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourDefault, Output: "Game is already paused."})
+	s.onInterface(&RconEnd{Command: "pause"})
+	res = <-results
+	assert.True(t, errors.Is(res.err, ErrAlreadyPaused))
+	assert.Len(t, res.transcript, 1)
+}
@@ -18,14 +18,16 @@ func New(hostname string, port int, password string) (s *Session, err error) {
 		State:                  NewState(),
 		StateEnabled:           true,
 		ShouldReconnectOnError: true,
+		ChatRateLimit:          time.Second,
+		ChatBurst:              5,
 		UpdateFrequencies:      map[enum.UpdateType]enum.UpdateFrequency{},
 		UserAgent:              "gopenttd (https://github.com/ropenttd/gopenttd)",
 		LastPong:               time.Now().UTC(),
 		Hostname:               hostname,
 		Port:                   port,
 		Password:               password,
+		RconTimeout:            DefaultRconTimeout,
 		rconQueue:              make(chan *rconRequest),
-		rconChan:               make(chan *rconResp),
 	}
 
 	// You should now call Open() so that events will trigger.
@@ -0,0 +1,223 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ropenttd/gopenttd/pkg/admin/rcon"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Server settings, read and changed through the console.
+
+// consoleQuery runs a console command, checks whether it failed, and has its output parsed by parse.
+func (s *Session) consoleQuery(ctx context.Context, parse func([]string) error, args ...string) (err error) {
+	command := consoleLine(args...)
+	output, err := s.RconContext(ctx, command)
+	if err != nil {
+		return err
+	}
+	if err = checkRconOutput(command, output); err != nil {
+		return err
+	}
+	if err = parse(rconLines(output)); err == rcon.ErrUnrecognised {
+		return &RconError{Command: command, Output: output, Err: err}
+	}
+	return err
+}
+
+// GetSetting returns the current value of the named server setting, e.g "difficulty.max_loan".
+// ErrUnknownSetting is returned if there's no such setting.
+func (s *Session) GetSetting(ctx context.Context, name string) (setting rcon.Setting, err error) {
+	err = s.consoleQuery(ctx, func(output []string) (err error) {
+		setting, err = rcon.ParseSetting(output)
+		return err
+	}, "setting", name)
+	return setting, err
+}
+
+// SetSetting changes the named server setting, and returns the value it ended up with.
+// value is a bool, a number or a string, as in rcon.Setting.
+// Numbers out of range are clamped by the server; ErrSettingNotApplied is returned along with the setting if the
+// value it ended up with isn't the one asked for.
+func (s *Session) SetSetting(ctx context.Context, name string, value interface{}) (setting rcon.Setting, err error) {
+	want := rcon.FormatSettingValue(value)
+	if _, err = s.consoleCommandContext(ctx, "setting", name, want); err != nil {
+		return setting, err
+	}
+	if setting, err = s.GetSetting(ctx, name); err != nil {
+		return setting, err
+	}
+	if !rcon.SettingValuesEqual(setting.Value, value) {
+		return setting, ErrSettingNotApplied
+	}
+	return setting, nil
+}
+
+// ListSettings returns all server settings whose names start with prefix, e.g "difficulty.", or all of them if
+// it's empty.
+// The console doesn't say what kind each setting is, so see rcon.ParseSettingList for the caveats on their values.
+func (s *Session) ListSettings(ctx context.Context, prefix string) (settings []rcon.Setting, err error) {
+	args := []string{"list_settings"}
+	if prefix != "" {
+		args = append(args, prefix)
+	}
+	err = s.consoleQuery(ctx, func(output []string) (err error) {
+		all, err := rcon.ParseSettingList(output)
+		// The server matches the filter anywhere in the name
+		for _, st := range all {
+			if strings.HasPrefix(st.Name, prefix) {
+				settings = append(settings, st)
+			}
+		}
+		return err
+	}, args...)
+	return settings, err
+}
+
+// A SettingsProfile is a set of server setting values, by name, which can be saved to a file and applied to other
+// servers to keep their configuration in step.
+type SettingsProfile map[string]interface{}
+
+// ExportSettings returns a profile of the server's current settings whose names start with prefix, or all of them
+// if it's empty.
+func (s *Session) ExportSettings(ctx context.Context, prefix string) (p SettingsProfile, err error) {
+	settings, err := s.ListSettings(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	p = make(SettingsProfile, len(settings))
+	for _, st := range settings {
+		p[st.Name] = st.Value
+	}
+	return p, nil
+}
+
+// isYAML returns whether the file should be written as YAML rather than JSON.
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// LoadSettingsProfile reads a profile from the given JSON or YAML file.
+func LoadSettingsProfile(path string) (p SettingsProfile, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is valid YAML, but YAML decodes "on" and "off" as bools, so stick to JSON for JSON files
+	if isYAML(path) {
+		err = yaml.Unmarshal(data, &p)
+	} else {
+		err = json.Unmarshal(data, &p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading settings profile %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Save writes the profile to the given file, as YAML if it ends in .yaml or .yml, otherwise as JSON.
+func (p SettingsProfile) Save(path string) (err error) {
+	var data []byte
+	if isYAML(path) {
+		data, err = yaml.Marshal(p)
+	} else {
+		data, err = json.MarshalIndent(p, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// A SettingChange is a setting whose value on the server differs from the one in a profile.
+type SettingChange struct {
+	Name string `json:"name"`
+	// Current is the server's value before the change, or nil if the server has no such setting.
+	Current interface{} `json:"current"`
+	// Wanted is the value in the profile.
+	Wanted interface{} `json:"wanted"`
+	// Applied is set once the change has been made.
+	Applied bool `json:"applied"`
+	// Err is why the change couldn't be made, if it couldn't. It's marshalled as its message.
+	Err error `json:"-"`
+}
+
+// MarshalJSON marshals the change, with Err as an "error" string.
+func (c SettingChange) MarshalJSON() ([]byte, error) {
+	// Marshal the fields as usual, without coming back here
+	type change SettingChange
+	res := struct {
+		change
+		Error string `json:"error,omitempty"`
+	}{change: change(c)}
+	if c.Err != nil {
+		res.Error = c.Err.Error()
+	}
+	return json.Marshal(res)
+}
+
+func (c SettingChange) String() string {
+	if c.Current == nil {
+		return fmt.Sprintf("%s: unknown setting", c.Name)
+	}
+	change := fmt.Sprintf("%s: %s -> %s", c.Name, rcon.FormatSettingValue(c.Current), rcon.FormatSettingValue(c.Wanted))
+	if c.Err != nil {
+		change += fmt.Sprintf(" (%s)", c.Err)
+	}
+	return change
+}
+
+// DiffSettings returns the settings in the profile whose values differ on the server, sorted by name.
+// Settings the server doesn't have are included, with their Err set to ErrUnknownSetting.
+func (s *Session) DiffSettings(ctx context.Context, p SettingsProfile) (changes []SettingChange, err error) {
+	current, err := s.ExportSettings(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for name, wanted := range p {
+		value, ok := current[name]
+		if !ok {
+			changes = append(changes, SettingChange{Name: name, Wanted: wanted, Err: ErrUnknownSetting})
+			continue
+		}
+		if !rcon.SettingValuesEqual(value, wanted) {
+			changes = append(changes, SettingChange{Name: name, Current: value, Wanted: wanted})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// ApplySettings changes the server's settings to match the profile, and returns what was changed.
+// If dryRun is set, nothing is changed, and the changes that would have been made are returned.
+// A change failing doesn't stop the others being made; check each change's Err. An error is only returned if the
+// server couldn't be asked at all, e.g ctx being done.
+func (s *Session) ApplySettings(ctx context.Context, p SettingsProfile, dryRun bool) (changes []SettingChange, err error) {
+	changes, err = s.DiffSettings(ctx, p)
+	if err != nil || dryRun {
+		return changes, err
+	}
+	for i := range changes {
+		c := &changes[i]
+		if c.Err != nil {
+			continue
+		}
+		_, c.Err = s.SetSetting(ctx, c.Name, c.Wanted)
+		var failed *RconError
+		if c.Err != nil && c.Err != ErrSettingNotApplied && !errors.As(c.Err, &failed) {
+			return changes, c.Err
+		}
+		c.Applied = c.Err == nil
+	}
+	return changes, nil
+}
@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/rcon"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplySettings(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	// respond answers the given command with the given output
+	respond := func(command string, output ...string) {
+		waitForRcon(s, command)
+		for _, l := range output {
+			s.onInterface(&Rcon{Output: l})
+		}
+		s.onInterface(&RconEnd{Command: command})
+	}
+	listSettings := func() {
+		respond("list_settings",
+			"All settings with their current value:",
+			"difficulty.max_loan = 300000",
+			"economy.inflation = on",
+			"network.server_name = Server: all welcome, failed players too",
+			"Use 'setting' command to change a value")
+	}
+	profile := SettingsProfile{
+		"difficulty.max_loan": float64(500000),
+		"economy.inflation":   true,
+		"network.server_name": "My Server",
+		"network.no_such":     1,
+	}
+
+	type result struct {
+		changes []SettingChange
+		err     error
+	}
+	results := make(chan result)
+	apply := func(dryRun bool) {
+		changes, err := s.ApplySettings(context.Background(), profile, dryRun)
+		results <- result{changes, err}
+	}
+
+	go apply(true)
+	listSettings()
+	res := <-results
+	assert.NoError(t, res.err)
+	if assert.Len(t, res.changes, 3) {
+		assert.Equal(t, "difficulty.max_loan: 300000 -> 500000", res.changes[0].String())
+		assert.Equal(t, "network.no_such: unknown setting", res.changes[1].String())
+		assert.Equal(t, `network.server_name: Server: all welcome, failed players too -> My Server`, res.changes[2].String())
+	}
+
+	go apply(false)
+	listSettings()
+	// The server clamps values that are out of range
+	respond("setting difficulty.max_loan 500000")
+	respond("setting difficulty.max_loan", "Current value for 'difficulty.max_loan' is: '400000' (min: 100000, max: 400000)")
+	respond(`setting network.server_name "My Server"`)
+	respond("setting network.server_name", "Current value for 'network.server_name' is: 'My Server'")
+	res = <-results
+	assert.NoError(t, res.err)
+	if assert.Len(t, res.changes, 3) {
+		assert.Equal(t, ErrSettingNotApplied, res.changes[0].Err)
+		assert.False(t, res.changes[0].Applied)
+		assert.True(t, errors.Is(res.changes[1].Err, ErrUnknownSetting))
+		assert.NoError(t, res.changes[2].Err)
+		assert.True(t, res.changes[2].Applied)
+
+		// Errors are marshalled as their message
+		data, err := json.Marshal(res.changes[:1])
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"name": "difficulty.max_loan", "current": 300000, "wanted": 500000, "applied": false, "error": "setting did not take the wanted value"}]`, string(data))
+	}
+}
+
+func TestGetSetting(t *testing.T) {
+	s := newTestSession()
+	listening := newTestConnection(t, s)
+	defer close(listening)
+	defer s.conn.Close()
+
+	type result struct {
+		setting rcon.Setting
+		err     error
+	}
+	results := make(chan result)
+	get := func(name string) {
+		setting, err := s.GetSetting(context.Background(), name)
+		results <- result{setting, err}
+	}
+
+	// Values can say anything
+	go get("network.server_name")
+	waitForRcon(s, "setting network.server_name")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourWarning, Output: "Current value for 'network.server_name' is: 'Failed Trains'"})
+	s.onInterface(&RconEnd{Command: "setting network.server_name"})
+	res := <-results
+	assert.NoError(t, res.err)
+	assert.Equal(t, "Failed Trains", res.setting.Value)
+
+	go get("network.no_such")
+	waitForRcon(s, "setting network.no_such")
+	s.onInterface(&Rcon{Colour: enum.ConsoleColourError, Output: "'network.no_such' is an unknown setting."})
+	s.onInterface(&RconEnd{Command: "setting network.no_such"})
+	res = <-results
+	assert.True(t, errors.Is(res.err, ErrUnknownSetting))
+}
+
+func TestSettingsProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopenttd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	p := SettingsProfile{"economy.inflation": false, "network.server_name": "off", "difficulty.max_loan": int64(300000)}
+	for _, name := range []string{"profile.json", "profile.yaml"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, p.Save(path))
+		loaded, err := LoadSettingsProfile(path)
+		assert.NoError(t, err)
+		assert.Len(t, loaded, 3)
+		assert.Equal(t, false, loaded["economy.inflation"], name)
+		assert.Equal(t, "off", loaded["network.server_name"], name)
+		assert.EqualValues(t, 300000, loaded["difficulty.max_loan"], name)
+	}
+}
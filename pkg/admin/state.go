@@ -10,9 +10,30 @@ import (
 )
 
 // A State contains the current known state of the server.
+// The state is updated concurrently as events arrive, so its data can only be read through Snapshot() or the
+// accessor methods, which take its lock.
 type State struct {
 	sync.RWMutex
+	snapshot StateSnapshot
 
+	// HistoryLength is the maximum number of economy samples kept in History for each company.
+	HistoryLength int
+
+	// SessionLength is the maximum number of finished client sessions kept in ClientSessions.
+	SessionLength int
+
+	// ConsoleLength and ChatLength are the maximum number of entries kept in ConsoleLog and ChatLog.
+	ConsoleLength int
+	ChatLength    int
+
+	// Clients and companies we haven't heard about since (re)connecting, see markUnconfirmed.
+	unconfirmedClients   map[enum.ClientID]bool
+	unconfirmedCompanies map[enum.CompanyID]bool
+}
+
+// A StateSnapshot is the data held by a State.
+// When obtained through State.Snapshot(), it is a deep copy that can be used freely without locking.
+type StateSnapshot struct {
 	// Dedicated is set if the server reports itself to be a Dedicated Server (instead of a Listen Server).
 	Dedicated bool `json:"dedicated"`
 	// Name is the server's advertised Hostname.
@@ -53,30 +74,58 @@ type State struct {
 	// or call State.Counts()
 
 	// Clients is a map of client IDs and client data.
-	Clients map[uint32]Client `json:"clients"`
+	Clients map[enum.ClientID]Client `json:"clients"`
 
 	// Companies is a map of company IDs and company data.
-	Companies map[uint8]Company `json:"companies"`
+	Companies map[enum.CompanyID]Company `json:"companies"`
+
+	// History is a map of company IDs and the economy samples recorded for them, oldest first.
+	// Use State.CompanyHistory() or State.Series() to query it.
+	History map[enum.CompanyID][]CompanySample `json:"history,omitempty"`
+
+	// ClientSessions is a record of the clients that have visited the server, oldest first.
+	// Use State.Sessions() and friends to query it.
+	ClientSessions []ClientSession `json:"sessions,omitempty"`
+
+	// Commands is a map of command IDs and command names, as sent by the server.
+	// These are only requested when subscribed to UpdateTypeCmdLogging, and change between server versions.
+	Commands map[uint16]string `json:"commands,omitempty"`
+
+	// ConsoleLog and ChatLog are the most recent console lines and chat messages, oldest first.
+	// Use State.SearchConsole() and State.SearchChat() to query them.
+	ConsoleLog []ConsoleLine `json:"console,omitempty"`
+	ChatLog    []ChatMessage `json:"chat,omitempty"`
 }
 
 // NewState creates an empty state.
 func NewState() *State {
 	return &State{
-		Clients:   map[uint32]Client{},
-		Companies: map[uint8]Company{},
+		snapshot: StateSnapshot{
+			Clients:   map[enum.ClientID]Client{},
+			Companies: map[enum.CompanyID]Company{},
+			History:   map[enum.CompanyID][]CompanySample{},
+			Commands:  map[uint16]string{},
+		},
+		HistoryLength: DefaultHistoryLength,
+		SessionLength: DefaultSessionLength,
+		ConsoleLength: DefaultConsoleLength,
+		ChatLength:    DefaultChatLength,
 	}
 }
 
 // Counts simply counts the number of companies, spectators, and clients connected.
 // Note that Clients is a count of ALL clients, INCLUDING spectators. (i.e Clients will always be bigger than Spectators)
 func (s *State) Counts() (clients int, spectators int, companies int) {
-	for _, c := range s.Clients {
+	s.RLock()
+	defer s.RUnlock()
+
+	for _, c := range s.snapshot.Clients {
 		clients++
-		if c.Company == 255 {
+		if c.Company.IsSpectator() {
 			spectators++
 		}
 	}
-	companies = len(s.Companies)
+	companies = len(s.snapshot.Companies)
 	return
 }
 
@@ -89,9 +138,11 @@ func (s *State) onProtocol(se *Session, r *Protocol) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	s.ProtocolVersion = r.Version
+	s.snapshot.ProtocolVersion = r.Version
 
 	// Reset polling rates
+	se.pollratesMu.Lock()
+	defer se.pollratesMu.Unlock()
 	se.pollrates = map[enum.UpdateType]uint16{}
 	for k, v := range r.Settings {
 		se.pollrates[enum.UpdateType(k)] = v
@@ -108,19 +159,43 @@ func (s *State) onWelcome(se *Session, r *Welcome) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	s.Name = r.Name
-	s.Version = r.Version
-	s.Dedicated = r.Dedicated
-	s.Map = r.Map
-	s.Seed = r.Seed
-	s.Landscape = util.OpenttdEnvironment(r.Landscape)
-	s.DateStart = util.DateFormat(r.StartDate)
-	s.MapWidth = r.MapWidth
-	s.MapHeight = r.MapHeight
+	if s.snapshot.Version != r.Version {
+		// Command IDs aren't stable between versions, so we'll need to ask again
+		s.snapshot.Commands = map[uint16]string{}
+	}
+
+	s.snapshot.Name = r.Name
+	s.snapshot.Version = r.Version
+	s.snapshot.Dedicated = r.Dedicated
+	s.snapshot.Map = r.Map
+	s.snapshot.Seed = r.Seed
+	s.snapshot.Landscape = util.OpenttdEnvironment(r.Landscape)
+	s.snapshot.DateStart = util.DateFormat(r.StartDate)
+	s.snapshot.MapWidth = r.MapWidth
+	s.snapshot.MapHeight = r.MapHeight
 
 	return nil
 }
 
+// OnCmdNames stores the given command names.
+func (s *State) onCmdNames(se *Session, r *CmdNames) (err error) {
+	if s == nil {
+		return ErrNilState
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.snapshot.Commands == nil {
+		s.snapshot.Commands = map[uint16]string{}
+	}
+	// The names can be split over several packets, so add to what we have rather than replacing it
+	for id, name := range r.Commands {
+		s.snapshot.Commands[id] = name
+	}
+	return nil
+}
+
 // OnDate takes a Date event and updates the current date in state.
 func (s *State) onDate(se *Session, r *Date) (err error) {
 	if s == nil {
@@ -130,35 +205,36 @@ func (s *State) onDate(se *Session, r *Date) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	s.DateCurrent = util.DateFormat(r.CurrentDate)
+	s.snapshot.DateCurrent = util.DateFormat(r.CurrentDate)
 	return
 }
 
 // OnClientJoin just requests further information about the client.
 func (s *State) onClientJoin(se *Session, r *ClientJoin) (err error) {
-	if s == nil || s.Clients == nil {
+	if s == nil || s.snapshot.Clients == nil {
 		return ErrNilState
 	}
 
 	s.Lock()
 	defer s.Unlock()
 
-	if _, ok := s.Clients[r.ID]; ok {
+	if _, ok := s.snapshot.Clients[r.ID]; ok {
 		// client in clients state, wtf?
 		se.log(LogWarning, "Joining Client %d appears to be in the state already - this shouldn't happen?", r.ID)
 	}
 
 	// If they already exist, we give them a new state anyway
-	s.Clients[r.ID] = Client{}
+	s.snapshot.Clients[r.ID] = Client{ID: r.ID}
+	s.startSession(r.ID, s.snapshot.DateCurrent)
 
 	// Poll for more information about this client
-	err = se.Poll(enum.UpdateTypeClientInfo, r.ID)
+	err = se.PollClient(r.ID)
 	return err
 }
 
 // OnClientInfo updates the information relating to the client that is referenced.
 func (s *State) onClientInfo(se *Session, r *ClientInfo) (err error) {
-	if s == nil || s.Clients == nil {
+	if s == nil || s.snapshot.Clients == nil {
 		return ErrNilState
 	}
 
@@ -166,7 +242,7 @@ func (s *State) onClientInfo(se *Session, r *ClientInfo) (err error) {
 	defer s.Unlock()
 
 	var cli Client
-	if res, ok := s.Clients[r.ID]; ok {
+	if res, ok := s.snapshot.Clients[r.ID]; ok {
 		// client in clients state
 		cli = res
 	} else {
@@ -174,20 +250,19 @@ func (s *State) onClientInfo(se *Session, r *ClientInfo) (err error) {
 		cli = Client{}
 	}
 
-	cli.Name = r.Name
-	cli.Language = util.OpenttdLanguage(r.Language)
-	cli.JoinDate = util.DateFormat(r.JoinDate)
-	cli.Address = net.ParseIP(r.Address)
-	cli.Company = r.Company
+	cli.ID = r.ID
+	cli.applyClientInfo(r)
 
-	s.Clients[r.ID] = cli
+	s.snapshot.Clients[r.ID] = cli
+	s.trackSession(cli)
+	delete(s.unconfirmedClients, r.ID)
 
 	return err
 }
 
 // OnClientUpdate fires when the client updates either their name or their company.
 func (s *State) onClientUpdate(se *Session, r *ClientUpdate) (err error) {
-	if s == nil || s.Clients == nil {
+	if s == nil || s.snapshot.Clients == nil {
 		return ErrNilState
 	}
 
@@ -195,7 +270,7 @@ func (s *State) onClientUpdate(se *Session, r *ClientUpdate) (err error) {
 	defer s.Unlock()
 
 	var cli Client
-	if res, ok := s.Clients[r.ID]; ok {
+	if res, ok := s.snapshot.Clients[r.ID]; ok {
 		// client in clients state
 		cli = res
 	} else {
@@ -203,10 +278,12 @@ func (s *State) onClientUpdate(se *Session, r *ClientUpdate) (err error) {
 		cli = Client{}
 	}
 
+	cli.ID = r.ID
 	cli.Name = r.Name
 	cli.Company = r.Company
 
-	s.Clients[r.ID] = cli
+	s.snapshot.Clients[r.ID] = cli
+	s.trackSession(cli)
 
 	return err
 }
@@ -220,41 +297,58 @@ func (s *State) onClientQuit(se *Session, r *ClientQuit) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	if _, ok := s.Clients[r.ID]; ok {
-		delete(s.Clients, r.ID)
+	if _, ok := s.snapshot.Clients[r.ID]; ok {
+		delete(s.snapshot.Clients, r.ID)
 	} else {
 		se.log(LogWarning, "Leaving Client %d does not appear to be in the state, ignoring", r.ID)
 	}
+	s.endSession(r.ID, nil)
+
+	return err
+}
+
+// OnClientError removes the client from the state, as the server disconnects clients that error.
+func (s *State) onClientError(se *Session, r *ClientError) (err error) {
+	if s == nil {
+		return ErrNilState
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.snapshot.Clients, r.ID)
+	reason := r.Error
+	s.endSession(r.ID, &reason)
 
 	return err
 }
 
 // OnCompanyNew just requests further information about the company.
 func (s *State) onCompanyNew(se *Session, r *CompanyNew) (err error) {
-	if s == nil || s.Companies == nil {
+	if s == nil || s.snapshot.Companies == nil {
 		return ErrNilState
 	}
 
 	s.Lock()
 	defer s.Unlock()
 
-	if _, ok := s.Companies[r.ID]; ok {
+	if _, ok := s.snapshot.Companies[r.ID]; ok {
 		// company in state, we obviously missed the removal
 		se.log(LogInformational, "New Company %d appears to be in the state already - we obviously missed the memo", r.ID)
 	}
 
 	// If they already exist, this gives them a new state anyway (which will be populated by the poll)
-	s.Companies[r.ID] = Company{}
+	s.snapshot.Companies[r.ID] = Company{ID: r.ID}
+	delete(s.snapshot.History, r.ID)
 
 	// Poll for more information about this company
-	// we have to cast our uint8 to a uint32 because that's what the poll packet expects
-	err = se.Poll(enum.UpdateTypeCompanyInfo, uint32(r.ID))
+	err = se.PollCompany(r.ID)
 	return err
 }
 
 // OnCompanyInfo updates the given company with the given information.
 func (s *State) onCompanyInfo(se *Session, r *CompanyInfo) (err error) {
-	if s == nil || s.Companies == nil {
+	if s == nil || s.snapshot.Companies == nil {
 		return ErrNilState
 	}
 
@@ -262,7 +356,7 @@ func (s *State) onCompanyInfo(se *Session, r *CompanyInfo) (err error) {
 	defer s.Unlock()
 
 	var com Company
-	if res, ok := s.Companies[r.ID]; ok {
+	if res, ok := s.snapshot.Companies[r.ID]; ok {
 		// company in state
 		com = res
 	} else {
@@ -270,22 +364,18 @@ func (s *State) onCompanyInfo(se *Session, r *CompanyInfo) (err error) {
 		com = Company{}
 	}
 
-	com.Name = r.Name
-	com.Manager = r.Manager
-	com.Colour = helpers.OpenttdColour(r.Colour)
-	com.Passworded = r.Password
-	// YearStart is an INTEGER containing the year of founding, NOT a time.Time
-	com.YearStart = r.StartDate
-	com.AI = r.IsAI
+	com.ID = r.ID
+	com.applyCompanyInfo(r)
 
-	s.Companies[r.ID] = com
+	s.snapshot.Companies[r.ID] = com
+	delete(s.unconfirmedCompanies, r.ID)
 
 	return err
 }
 
 // OnCompanyUpdate when the company updates something about their state.
 func (s *State) onCompanyUpdate(se *Session, r *CompanyUpdate) (err error) {
-	if s == nil || s.Companies == nil {
+	if s == nil || s.snapshot.Companies == nil {
 		return ErrNilState
 	}
 
@@ -293,7 +383,7 @@ func (s *State) onCompanyUpdate(se *Session, r *CompanyUpdate) (err error) {
 	defer s.Unlock()
 
 	var com Company
-	if res, ok := s.Companies[r.ID]; ok {
+	if res, ok := s.snapshot.Companies[r.ID]; ok {
 		// company in state
 		com = res
 	} else {
@@ -301,13 +391,14 @@ func (s *State) onCompanyUpdate(se *Session, r *CompanyUpdate) (err error) {
 		com = Company{}
 	}
 
+	com.ID = r.ID
 	com.Name = r.Name
 	com.Manager = r.Manager
 	com.Colour = helpers.OpenttdColour(r.Colour)
 	com.Passworded = r.Password
 	com.Bankruptcy = r.BankruptcyQuarters
 
-	if se.State.ProtocolVersion <= 2 {
+	if se.State.snapshot.ProtocolVersion <= 2 {
 		// Company shares were removed in OpenTTD 14.0.
 		com.Share1 = r.Share1
 		com.Share2 = r.Share2
@@ -315,7 +406,7 @@ func (s *State) onCompanyUpdate(se *Session, r *CompanyUpdate) (err error) {
 		com.Share4 = r.Share4
 	}
 
-	s.Companies[r.ID] = com
+	s.snapshot.Companies[r.ID] = com
 
 	return err
 }
@@ -329,8 +420,11 @@ func (s *State) onCompanyRemove(se *Session, r *CompanyRemove) (err error) {
 	s.Lock()
 	defer s.Unlock()
 
-	if _, ok := s.Companies[r.ID]; ok {
-		delete(s.Companies, r.ID)
+	// Company IDs are reused, so the history has to go too
+	delete(s.snapshot.History, r.ID)
+
+	if _, ok := s.snapshot.Companies[r.ID]; ok {
+		delete(s.snapshot.Companies, r.ID)
 	} else {
 		se.log(LogWarning, "Dissolved Company %d does not appear to be in the state, ignoring", r.ID)
 	}
@@ -348,7 +442,7 @@ func (s *State) onCompanyEconomy(se *Session, r *CompanyEconomy) (err error) {
 	defer s.Unlock()
 
 	var com Company
-	if res, ok := s.Companies[r.ID]; ok {
+	if res, ok := s.snapshot.Companies[r.ID]; ok {
 		// company in state
 		com = res
 	} else {
@@ -356,18 +450,11 @@ func (s *State) onCompanyEconomy(se *Session, r *CompanyEconomy) (err error) {
 		com = Company{}
 	}
 
-	com.Money = r.Money
-	com.Loan = r.Loan
-	com.Income = r.Income
-	com.CargoThisQuarter = r.CargoThisQuarter
-	com.ValueLastQuarter = r.ValueLastQuarter
-	com.PerformanceLastQuarter = r.PerformanceLastQuarter
-	com.CargoLastQuarter = r.CargoLastQuarter
-	com.ValuePreviousQuarter = r.ValuePreviousQuarter
-	com.PerformancePreviousQuarter = r.PerformancePreviousQuarter
-	com.CargoPreviousQuarter = r.CargoPreviousQuarter
+	com.ID = r.ID
+	com.applyCompanyEconomy(r)
 
-	s.Companies[r.ID] = com
+	s.snapshot.Companies[r.ID] = com
+	s.recordCompanyEconomy(r)
 
 	return err
 
@@ -383,7 +470,7 @@ func (s *State) onCompanyStats(se *Session, r *CompanyStats) (err error) {
 	defer s.Unlock()
 
 	var com Company
-	if res, ok := s.Companies[r.ID]; ok {
+	if res, ok := s.snapshot.Companies[r.ID]; ok {
 		// company in state
 		com = res
 	} else {
@@ -391,6 +478,52 @@ func (s *State) onCompanyStats(se *Session, r *CompanyStats) (err error) {
 		com = Company{Vehicles: util.OpenttdTypeCounts{}, Stations: util.OpenttdTypeCounts{}}
 	}
 
+	com.ID = r.ID
+	com.applyCompanyStats(r)
+
+	s.snapshot.Companies[r.ID] = com
+	s.recordCompanyStats(r)
+
+	return err
+
+}
+
+// applyClientInfo updates the client with the information in a ClientInfo event.
+func (cli *Client) applyClientInfo(r *ClientInfo) {
+	cli.Name = r.Name
+	cli.Language = util.OpenttdLanguage(r.Language)
+	cli.JoinDate = util.DateFormat(r.JoinDate)
+	cli.Address = net.ParseIP(r.Address)
+	cli.Company = r.Company
+}
+
+// applyCompanyInfo updates the company with the information in a CompanyInfo event.
+func (com *Company) applyCompanyInfo(r *CompanyInfo) {
+	com.Name = r.Name
+	com.Manager = r.Manager
+	com.Colour = helpers.OpenttdColour(r.Colour)
+	com.Passworded = r.Password
+	// YearStart is an INTEGER containing the year of founding, NOT a time.Time
+	com.YearStart = r.StartDate
+	com.AI = r.IsAI
+}
+
+// applyCompanyEconomy updates the company with the statistics in a CompanyEconomy event.
+func (com *Company) applyCompanyEconomy(r *CompanyEconomy) {
+	com.Money = r.Money
+	com.Loan = r.Loan
+	com.Income = r.Income
+	com.CargoThisQuarter = r.CargoThisQuarter
+	com.ValueLastQuarter = r.ValueLastQuarter
+	com.PerformanceLastQuarter = r.PerformanceLastQuarter
+	com.CargoLastQuarter = r.CargoLastQuarter
+	com.ValuePreviousQuarter = r.ValuePreviousQuarter
+	com.PerformancePreviousQuarter = r.PerformancePreviousQuarter
+	com.CargoPreviousQuarter = r.CargoPreviousQuarter
+}
+
+// applyCompanyStats updates the company with the vehicle and station counts in a CompanyStats event.
+func (com *Company) applyCompanyStats(r *CompanyStats) {
 	com.Vehicles.Train = r.Trains
 	com.Vehicles.Truck = r.Lorries
 	com.Vehicles.Bus = r.Buses
@@ -402,11 +535,6 @@ func (s *State) onCompanyStats(se *Session, r *CompanyStats) (err error) {
 	com.Stations.Bus = r.BusStops
 	com.Stations.Aircraft = r.Airports
 	com.Stations.Ship = r.Harbours
-
-	s.Companies[r.ID] = com
-
-	return err
-
 }
 
 // OnInterface handles all events related to states.
@@ -420,6 +548,8 @@ func (s *State) OnInterface(se *Session, i interface{}) (err error) {
 		return s.onProtocol(se, r)
 	case *Welcome:
 		return s.onWelcome(se, r)
+	case *CmdNames:
+		return s.onCmdNames(se, r)
 	}
 
 	if !se.StateEnabled {
@@ -438,6 +568,8 @@ func (s *State) OnInterface(se *Session, i interface{}) (err error) {
 		err = s.onClientUpdate(se, r)
 	case *ClientQuit:
 		err = s.onClientQuit(se, r)
+	case *ClientError:
+		err = s.onClientError(se, r)
 	case *CompanyNew:
 		err = s.onCompanyNew(se, r)
 	case *CompanyInfo:
@@ -450,6 +582,10 @@ func (s *State) OnInterface(se *Session, i interface{}) (err error) {
 		err = s.onCompanyEconomy(se, r)
 	case *CompanyStats:
 		err = s.onCompanyStats(se, r)
+	case *Console:
+		err = s.onConsole(se, r)
+	case *Chat:
+		err = s.onChat(se, r)
 	}
 	return err
 }
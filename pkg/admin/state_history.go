@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
+	"time"
+)
+
+// DefaultHistoryLength is the default number of samples kept per company by a new State.
+// With quarterly economy updates, this is 25 years of history.
+const DefaultHistoryLength = 100
+
+// A CompanySample is a record of a company's economy and statistics on a given game date.
+// Samples are built from CompanyEconomy and CompanyStats events: fields that weren't updated on the date of the
+// sample are carried over from the sample before it.
+type CompanySample struct {
+	// Date is the game date the sample was taken on.
+	Date time.Time `json:"date"`
+	// The amount of disposable cash the company had.
+	Money util.Money `json:"cash"`
+	// The company's loan.
+	Loan util.Money `json:"loan"`
+	// The company's income.
+	Income util.Money `json:"income"`
+	// Company value in the last full quarter.
+	Value util.Money `json:"value"`
+	// Company performance index in the last full quarter, out of 1000.
+	Performance uint16 `json:"performance"`
+	// Cargo delivered in the last full quarter.
+	Cargo uint16 `json:"cargo"`
+	// A count of the vehicles and stations the company had.
+	Vehicles util.OpenttdTypeCounts `json:"vehicle_count"`
+	Stations util.OpenttdTypeCounts `json:"station_count"`
+}
+
+// Metric is a numeric property of a company, which can be tracked over time or ranked.
+type Metric uint8
+
+const (
+	MetricValue Metric = iota
+	MetricMoney
+	MetricIncome
+	MetricLoan
+	MetricPerformance
+	MetricCargo
+	MetricVehicles
+	MetricStations
+)
+
+// String returns the string representation of the Metric.
+func (m Metric) String() string {
+	names := [...]string{
+		"Value",
+		"Money",
+		"Income",
+		"Loan",
+		"Performance",
+		"Cargo",
+		"Vehicles",
+		"Stations",
+	}
+	// prevent panics for out of range lookups
+	if m > MetricStations {
+		return "Unknown"
+	}
+	return names[m]
+}
+
+// of returns the value of the metric in the given sample.
+func (m Metric) of(cs CompanySample) int64 {
+	switch m {
+	case MetricValue:
+		return int64(cs.Value)
+	case MetricMoney:
+		return int64(cs.Money)
+	case MetricIncome:
+		return int64(cs.Income)
+	case MetricLoan:
+		return int64(cs.Loan)
+	case MetricPerformance:
+		return int64(cs.Performance)
+	case MetricCargo:
+		return int64(cs.Cargo)
+	case MetricVehicles:
+		return int64(cs.Vehicles.Total())
+	case MetricStations:
+		return int64(cs.Stations.Total())
+	}
+	return 0
+}
+
+// A Point is a single value in a time series.
+type Point struct {
+	Date  time.Time `json:"date"`
+	Value int64     `json:"value"`
+}
+
+// CompanyHistory returns the recorded samples for the given company, oldest first.
+// Samples are keyed by the current game date, so subscribe to UpdateTypeDate updates to keep it accurate.
+func (s *State) CompanyHistory(id enum.CompanyID) []CompanySample {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return append([]CompanySample(nil), s.snapshot.History[id]...)
+}
+
+// Series returns the history of a single metric for the given company, oldest first.
+func (s *State) Series(id enum.CompanyID, m Metric) []Point {
+	history := s.CompanyHistory(id)
+
+	points := make([]Point, len(history))
+	for i, cs := range history {
+		points[i] = Point{Date: cs.Date, Value: m.of(cs)}
+	}
+	return points
+}
+
+// sample returns the sample for the given company on the current game date, creating it if required.
+// The state lock must be held when calling this.
+func (s *State) sample(id enum.CompanyID) *CompanySample {
+	if s.snapshot.History == nil {
+		s.snapshot.History = map[enum.CompanyID][]CompanySample{}
+	}
+
+	history := s.snapshot.History[id]
+	if n := len(history); n > 0 && history[n-1].Date.Equal(s.snapshot.DateCurrent) {
+		return &history[n-1]
+	}
+
+	var cs CompanySample
+	if n := len(history); n > 0 {
+		// Carry over whatever we knew before
+		cs = history[n-1]
+	}
+	cs.Date = s.snapshot.DateCurrent
+	history = append(history, cs)
+
+	limit := s.HistoryLength
+	if limit <= 0 {
+		limit = DefaultHistoryLength
+	}
+	if len(history) > limit {
+		// Reslicing drops the oldest samples without copying the rest; append moves what's kept to a new array
+		// once the old one is full, so the dropped samples are freed in batches.
+		history = history[len(history)-limit:]
+	}
+
+	s.snapshot.History[id] = history
+	return &history[len(history)-1]
+}
+
+// recordCompanyEconomy adds the economy data from the event to the company's history.
+// The state lock must be held when calling this.
+func (s *State) recordCompanyEconomy(r *CompanyEconomy) {
+	cs := s.sample(r.ID)
+	cs.Money = r.Money
+	cs.Loan = r.Loan
+	cs.Income = r.Income
+	cs.Value = r.ValueLastQuarter
+	cs.Performance = r.PerformanceLastQuarter
+	cs.Cargo = r.CargoLastQuarter
+}
+
+// recordCompanyStats adds the statistics from the event to the company's history.
+// The state lock must be held when calling this.
+func (s *State) recordCompanyStats(r *CompanyStats) {
+	cs := s.sample(r.ID)
+	cs.Vehicles = util.OpenttdTypeCounts{
+		Train:    r.Trains,
+		Truck:    r.Lorries,
+		Bus:      r.Buses,
+		Aircraft: r.Planes,
+		Ship:     r.Ships,
+	}
+	cs.Stations = util.OpenttdTypeCounts{
+		Train:    r.TrainStations,
+		Truck:    r.LorryStations,
+		Bus:      r.BusStops,
+		Aircraft: r.Airports,
+		Ship:     r.Harbours,
+	}
+}
@@ -0,0 +1,141 @@
+package admin
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseMetric returns the Metric with the given name (case insensitive), e.g "value" or "Vehicles".
+// The second return value is false if there is no such metric.
+func ParseMetric(name string) (Metric, bool) {
+	for m := MetricValue; m <= MetricStations; m++ {
+		if strings.EqualFold(m.String(), name) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// LeaderboardOptions changes which companies are ranked by State.Leaderboard().
+type LeaderboardOptions struct {
+	// ExcludeAI leaves AI companies out of the rankings.
+	ExcludeAI bool
+}
+
+// A Rank is the position of a company on a leaderboard.
+type Rank struct {
+	// Rank is the 1-based position of the company. Companies with the same value share a rank.
+	Rank int `json:"rank"`
+	// PreviousRank is the position the company had at the end of the previous quarter, or 0 if it wasn't ranked.
+	PreviousRank int `json:"previous_rank"`
+	// Change is how many places the company has moved up (or down, if negative) since the previous quarter.
+	// It is 0 if the company wasn't ranked in the previous quarter.
+	Change int `json:"change"`
+	// Company is a copy of the ranked company.
+	Company Company `json:"company"`
+	// Value and PreviousValue are the values of the metric the company was ranked by.
+	Value         int64 `json:"value"`
+	PreviousValue int64 `json:"previous_value"`
+}
+
+// Leaderboard ranks the known companies by the given metric, highest first.
+// Value, performance and cargo are ranked by their figures for the last full quarter. Rank changes are worked out
+// from the figures the server gives for the quarter before that, or from the History for the other metrics.
+func (s *State) Leaderboard(m Metric, opts LeaderboardOptions) []Rank {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	var ranks, previous []*Rank
+	quarter := quarterStart(s.snapshot.DateCurrent)
+	for _, com := range s.snapshot.Companies {
+		if opts.ExcludeAI && com.AI {
+			continue
+		}
+		r := &Rank{Company: com, Value: m.of(com.sample())}
+		if v, ok := s.previousQuarter(m, com, quarter); ok {
+			r.PreviousValue = v
+			previous = append(previous, r)
+		}
+		ranks = append(ranks, r)
+	}
+
+	rank(previous, func(r *Rank) int64 { return r.PreviousValue }, func(r *Rank, n int) { r.PreviousRank = n })
+	rank(ranks, func(r *Rank) int64 { return r.Value }, func(r *Rank, n int) { r.Rank = n })
+
+	res := make([]Rank, len(ranks))
+	for i, r := range ranks {
+		if r.PreviousRank != 0 {
+			r.Change = r.PreviousRank - r.Rank
+		}
+		res[i] = *r
+	}
+	return res
+}
+
+// rank sorts the entries by value (highest first, then by company ID) and numbers them.
+// Entries with equal values share a rank, i.e 1, 2, 2, 4.
+func rank(entries []*Rank, value func(*Rank) int64, set func(*Rank, int)) {
+	sort.Slice(entries, func(i, j int) bool {
+		if a, b := value(entries[i]), value(entries[j]); a != b {
+			return a > b
+		}
+		return entries[i].Company.ID < entries[j].Company.ID
+	})
+
+	n := 0
+	for i, r := range entries {
+		if i == 0 || value(r) != value(entries[i-1]) {
+			n = i + 1
+		}
+		set(r, n)
+	}
+}
+
+// previousQuarter returns the value of the metric for the company at the end of the quarter before the one starting
+// at the given date. The second return value is false if it isn't known.
+// The state lock must be held when calling this.
+func (s *State) previousQuarter(m Metric, com Company, quarter time.Time) (int64, bool) {
+	history := s.snapshot.History[com.ID]
+
+	// The server tells us these itself, as long as we've had an economy update (which is also recorded in the history)
+	switch m {
+	case MetricValue:
+		return int64(com.ValuePreviousQuarter), len(history) > 0
+	case MetricPerformance:
+		return int64(com.PerformancePreviousQuarter), len(history) > 0
+	case MetricCargo:
+		return int64(com.CargoPreviousQuarter), len(history) > 0
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Date.Before(quarter) {
+			return m.of(history[i]), true
+		}
+	}
+	return 0, false
+}
+
+// quarterStart returns the first day of the quarter the date is in.
+func quarterStart(t time.Time) time.Time {
+	month := (t.Month()-1)/3*3 + 1
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// sample returns the company's current figures as a CompanySample, so they can be measured with a Metric.
+func (com Company) sample() CompanySample {
+	return CompanySample{
+		Money:       com.Money,
+		Loan:        com.Loan,
+		Income:      com.Income,
+		Value:       com.ValueLastQuarter,
+		Performance: com.PerformanceLastQuarter,
+		Cargo:       com.CargoLastQuarter,
+		Vehicles:    com.Vehicles,
+		Stations:    com.Stations,
+	}
+}
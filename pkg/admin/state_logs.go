@@ -0,0 +1,201 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultConsoleLength and DefaultChatLength are the default number of console lines and chat messages
+// kept by a new State.
+const (
+	DefaultConsoleLength = 500
+	DefaultChatLength    = 500
+)
+
+// A ConsoleLine is a line of text that was printed on the server's console.
+type ConsoleLine struct {
+	// Time is the real (UTC) time the line was received.
+	Time time.Time `json:"time"`
+	// Origin is where the text came from, e.g "console", or "net" for network related messages.
+	Origin string `json:"origin"`
+	// Message is the text itself.
+	Message string `json:"message"`
+}
+
+// A ChatMessage is a message that was sent in the in-game chat.
+type ChatMessage struct {
+	// Time is the real (UTC) time the message was received.
+	Time time.Time `json:"time"`
+	// Date is the game date the message was sent on.
+	Date time.Time `json:"date"`
+	// Action is the kind of message, e.g ActionChat or ActionGiveMoney.
+	Action enum.Action `json:"action"`
+	// Destination is who the message was sent to, e.g DestinationBroadcast or DestinationTeam.
+	Destination enum.Destination `json:"destination"`
+	// Client is the ID of the client who sent the message.
+	Client enum.ClientID `json:"client"`
+	// ClientName is the name the client had when they sent the message, if it was known.
+	ClientName string `json:"client_name"`
+	// Company is the company the client was playing in when they sent the message.
+	Company enum.CompanyID `json:"company"`
+	// CompanyName is the name of that company, if it was known.
+	CompanyName string `json:"company_name"`
+	// Message is the text of the message.
+	Message string `json:"message"`
+	// Money is the amount of money given, for ActionGiveMoney messages.
+	Money util.Money `json:"money"`
+}
+
+// A LogFilter selects console lines or chat messages in a search.
+// Fields left at their zero value match everything.
+type LogFilter struct {
+	// Text matches entries containing the given text (case insensitive).
+	Text string
+	// Regexp matches entries whose text matches the given regular expression.
+	Regexp *regexp.Regexp
+	// From and To match entries received within the given times (inclusive).
+	From time.Time
+	To   time.Time
+	// Client matches chat messages sent by the given client.
+	// Console lines don't have a client, so this is ignored when searching the console.
+	Client enum.ClientID
+	// Origin matches console lines from the given origin, e.g "net".
+	// This is ignored when searching chat.
+	Origin string
+}
+
+// matches returns whether an entry with the given time and text passes the filter.
+func (f LogFilter) matches(t time.Time, text string) bool {
+	if !f.From.IsZero() && t.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && t.After(f.To) {
+		return false
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(text), strings.ToLower(f.Text)) {
+		return false
+	}
+	if f.Regexp != nil && !f.Regexp.MatchString(text) {
+		return false
+	}
+	return true
+}
+
+// Console returns the recorded console lines, oldest first.
+func (s *State) Console() []ConsoleLine {
+	return s.SearchConsole(LogFilter{})
+}
+
+// SearchConsole returns the recorded console lines matching the filter, oldest first.
+func (s *State) SearchConsole(f LogFilter) []ConsoleLine {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	var res []ConsoleLine
+	for _, l := range s.snapshot.ConsoleLog {
+		if f.Origin != "" && l.Origin != f.Origin {
+			continue
+		}
+		if f.matches(l.Time, l.Message) {
+			res = append(res, l)
+		}
+	}
+	return res
+}
+
+// Chat returns the recorded chat messages, oldest first.
+func (s *State) Chat() []ChatMessage {
+	return s.SearchChat(LogFilter{})
+}
+
+// SearchChat returns the recorded chat messages matching the filter, oldest first.
+func (s *State) SearchChat(f LogFilter) []ChatMessage {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	var res []ChatMessage
+	for _, m := range s.snapshot.ChatLog {
+		if f.Client.IsValid() && m.Client != f.Client {
+			continue
+		}
+		if f.matches(m.Time, m.Message) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// OnConsole records the console line.
+func (s *State) onConsole(se *Session, r *Console) (err error) {
+	if s == nil {
+		return ErrNilState
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.snapshot.ConsoleLog = append(s.snapshot.ConsoleLog, ConsoleLine{
+		Time:    time.Now().UTC(),
+		Origin:  r.Origin,
+		Message: r.Message,
+	})
+
+	limit := s.ConsoleLength
+	if limit <= 0 {
+		limit = DefaultConsoleLength
+	}
+	if len(s.snapshot.ConsoleLog) > limit {
+		// Reslicing frees the dropped lines in batches, as with company history
+		s.snapshot.ConsoleLog = s.snapshot.ConsoleLog[len(s.snapshot.ConsoleLog)-limit:]
+	}
+	return nil
+}
+
+// OnChat records the chat message, along with who sent it.
+func (s *State) onChat(se *Session, r *Chat) (err error) {
+	if s == nil {
+		return ErrNilState
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	msg := ChatMessage{
+		Time:        time.Now().UTC(),
+		Date:        s.snapshot.DateCurrent,
+		Action:      r.Action,
+		Destination: r.Destination,
+		Client:      r.ID,
+		Company:     enum.CompanyIDSpectator,
+		Message:     r.Message,
+		Money:       r.Money,
+	}
+	if cli, ok := s.snapshot.Clients[r.ID]; ok {
+		msg.ClientName = cli.Name
+		msg.Company = cli.Company
+		if com, ok := s.snapshot.Companies[cli.Company]; ok {
+			msg.CompanyName = com.Name
+		}
+	}
+	s.snapshot.ChatLog = append(s.snapshot.ChatLog, msg)
+
+	limit := s.ChatLength
+	if limit <= 0 {
+		limit = DefaultChatLength
+	}
+	if len(s.snapshot.ChatLog) > limit {
+		s.snapshot.ChatLog = s.snapshot.ChatLog[len(s.snapshot.ChatLog)-limit:]
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"sort"
+	"strings"
+)
+
+// ClientsInCompany returns the clients playing in the given company, ordered by client ID.
+func (s *State) ClientsInCompany(id enum.CompanyID) []Client {
+	return s.findClients(func(cli Client) bool {
+		return cli.Company == id
+	})
+}
+
+// Spectators returns the clients that aren't playing in a company, ordered by client ID.
+func (s *State) Spectators() []Client {
+	return s.ClientsInCompany(enum.CompanyIDSpectator)
+}
+
+// CompanyOfClient returns the company the given client is playing in.
+// The second return value is false if the client isn't known, or is spectating.
+func (s *State) CompanyOfClient(id enum.ClientID) (Company, bool) {
+	if s == nil {
+		return Company{}, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	cli, ok := s.snapshot.Clients[id]
+	if !ok {
+		return Company{}, false
+	}
+	com, ok := s.snapshot.Companies[cli.Company]
+	return com, ok
+}
+
+// FindClientsByName returns the clients whose names match the pattern, best matches first.
+// Matching is case insensitive and fuzzy: exact matches come first, then names starting with the pattern, then
+// names containing it, and finally names containing all of its characters in order (i.e "jdo" matches "John Doe").
+func (s *State) FindClientsByName(pattern string) []Client {
+	pattern = strings.ToLower(pattern)
+
+	scores := map[enum.ClientID]int{}
+	clients := s.findClients(func(cli Client) bool {
+		score := nameScore(strings.ToLower(cli.Name), pattern)
+		scores[cli.ID] = score
+		return score > 0
+	})
+
+	// findClients sorted by ID, so this keeps matches of the same quality in that order
+	sort.SliceStable(clients, func(i, j int) bool {
+		return scores[clients[i].ID] > scores[clients[j].ID]
+	})
+	return clients
+}
+
+// nameScore returns how well the (lowercase) name matches the (lowercase) pattern, or 0 if it doesn't.
+func nameScore(name, pattern string) int {
+	switch {
+	case name == pattern:
+		return 4
+	case strings.HasPrefix(name, pattern):
+		return 3
+	case strings.Contains(name, pattern):
+		return 2
+	}
+
+	// Look for the characters of the pattern in order
+	rest := []rune(pattern)
+	for _, r := range name {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+	if len(rest) == 0 {
+		return 1
+	}
+	return 0
+}
+
+// EmptyCompanies returns the companies that no client is playing in, ordered by company ID.
+func (s *State) EmptyCompanies() []Company {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	occupied := map[enum.CompanyID]bool{}
+	for _, cli := range s.snapshot.Clients {
+		occupied[cli.Company] = true
+	}
+	return s.findCompanies(func(com Company) bool {
+		return !occupied[com.ID]
+	})
+}
+
+// AICompanies returns the companies controlled by an AI, ordered by company ID.
+func (s *State) AICompanies() []Company {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.findCompanies(func(com Company) bool {
+		return com.AI
+	})
+}
+
+// findClients returns copies of the clients matching the filter, ordered by client ID.
+func (s *State) findClients(filter func(Client) bool) []Client {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	var clients []Client
+	for _, cli := range s.snapshot.Clients {
+		if filter(cli) {
+			clients = append(clients, cli.copy())
+		}
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID < clients[j].ID
+	})
+	return clients
+}
+
+// findCompanies returns the companies matching the filter, ordered by company ID.
+// The state lock must be held when calling this.
+func (s *State) findCompanies(filter func(Company) bool) []Company {
+	var companies []Company
+	for _, com := range s.snapshot.Companies {
+		if filter(com) {
+			companies = append(companies, com)
+		}
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return companies[i].ID < companies[j].ID
+	})
+	return companies
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+)
+
+// Restore replaces the contents of the state with the given snapshot, i.e one loaded from a StateStore.
+// Restored clients and companies are kept until the server has been polled, and are then dropped if the server
+// no longer knows about them.
+func (s *State) Restore(ss StateSnapshot) {
+	if s == nil {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.snapshot = ss.copy()
+}
+
+// markUnconfirmed flags every known client and company as unconfirmed, ready for the server to be polled for them.
+func (s *State) markUnconfirmed() {
+	s.Lock()
+	defer s.Unlock()
+
+	s.unconfirmedClients = make(map[enum.ClientID]bool, len(s.snapshot.Clients))
+	for id := range s.snapshot.Clients {
+		s.unconfirmedClients[id] = true
+	}
+	s.unconfirmedCompanies = make(map[enum.CompanyID]bool, len(s.snapshot.Companies))
+	for id := range s.snapshot.Companies {
+		s.unconfirmedCompanies[id] = true
+	}
+}
+
+// pruneUnconfirmed drops every client and company that the server hasn't told us about since markUnconfirmed.
+func (s *State) pruneUnconfirmed(se *Session) {
+	s.Lock()
+	defer s.Unlock()
+
+	for id := range s.unconfirmedClients {
+		se.log(LogInformational, "Client %d is no longer on the server, removing it from the state", id)
+		delete(s.snapshot.Clients, id)
+		s.endSession(id, nil)
+	}
+	for id := range s.unconfirmedCompanies {
+		se.log(LogInformational, "Company %d no longer exists, removing it from the state", id)
+		delete(s.snapshot.Companies, id)
+		delete(s.snapshot.History, id)
+	}
+
+	s.unconfirmedClients = nil
+	s.unconfirmedCompanies = nil
+}
@@ -0,0 +1,228 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultSessionLength is the default number of finished client sessions kept by a new State.
+const DefaultSessionLength = 1000
+
+// A ClientSession is a record of one visit of a client to the server, from joining to leaving.
+type ClientSession struct {
+	// ClientID is the ID the client had during the session. Client IDs aren't reused, but they are reset when the
+	// server restarts, so use Name to identify players.
+	ClientID enum.ClientID `json:"client_id"`
+	// Name is the last name the client used during the session.
+	Name string `json:"name"`
+	// Address is the client's IP address, if it was known.
+	Address net.IP `json:"address"`
+	// Joined and Left are the real (UTC) times the client joined and left the server.
+	// For clients that were already connected when we connected, Joined is when we first saw them.
+	// Left is zero while the client is still connected.
+	Joined time.Time `json:"joined"`
+	Left   time.Time `json:"left"`
+	// JoinedDate and LeftDate are the game dates the client joined and left the server.
+	JoinedDate time.Time `json:"date_joined"`
+	LeftDate   time.Time `json:"date_left"`
+	// Companies are the companies the client played in during the session, in the order they joined them.
+	Companies []enum.CompanyID `json:"companies"`
+	// QuitReason is the error that caused the client to leave, or nil if they quit normally (or haven't left).
+	QuitReason *enum.NetError `json:"quit_reason,omitempty"`
+}
+
+// Connected returns whether the client is still connected.
+func (cs ClientSession) Connected() bool {
+	return cs.Left.IsZero()
+}
+
+// Duration returns how long the session lasted, or has lasted so far if the client is still connected.
+func (cs ClientSession) Duration() time.Duration {
+	if cs.Connected() {
+		return time.Now().UTC().Sub(cs.Joined)
+	}
+	return cs.Left.Sub(cs.Joined)
+}
+
+// overlaps returns whether any part of the session happened between from and to.
+func (cs ClientSession) overlaps(from, to time.Time) bool {
+	left := cs.Left
+	if cs.Connected() {
+		left = time.Now().UTC()
+	}
+	return !cs.Joined.After(to) && !left.Before(from)
+}
+
+// copy returns a deep copy of the session.
+func (cs ClientSession) copy() ClientSession {
+	if cs.Address != nil {
+		cs.Address = append(net.IP(nil), cs.Address...)
+	}
+	cs.Companies = append([]enum.CompanyID(nil), cs.Companies...)
+	if cs.QuitReason != nil {
+		reason := *cs.QuitReason
+		cs.QuitReason = &reason
+	}
+	return cs
+}
+
+// Sessions returns every recorded client session, oldest first.
+func (s *State) Sessions() []ClientSession {
+	return s.findSessions(func(cs ClientSession) bool {
+		return true
+	})
+}
+
+// SessionsByName returns the sessions of clients using the given name (case insensitive), oldest first.
+func (s *State) SessionsByName(name string) []ClientSession {
+	return s.findSessions(func(cs ClientSession) bool {
+		return strings.EqualFold(cs.Name, name)
+	})
+}
+
+// SessionsBetween returns the sessions during which the client was connected at any point between from and to,
+// oldest first.
+func (s *State) SessionsBetween(from, to time.Time) []ClientSession {
+	return s.findSessions(func(cs ClientSession) bool {
+		return cs.overlaps(from, to)
+	})
+}
+
+// Playtime returns the total time clients using the given name (case insensitive) have spent on the server.
+func (s *State) Playtime(name string) (total time.Duration) {
+	for _, cs := range s.SessionsByName(name) {
+		total += cs.Duration()
+	}
+	return total
+}
+
+// Playtimes returns the total time spent on the server by every client name that has been seen.
+func (s *State) Playtimes() map[string]time.Duration {
+	res := map[string]time.Duration{}
+	for _, cs := range s.Sessions() {
+		res[cs.Name] += cs.Duration()
+	}
+	return res
+}
+
+// findSessions returns copies of the sessions matching the filter, oldest first.
+func (s *State) findSessions(filter func(ClientSession) bool) []ClientSession {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	var res []ClientSession
+	for _, cs := range s.snapshot.ClientSessions {
+		if filter(cs) {
+			res = append(res, cs.copy())
+		}
+	}
+	return res
+}
+
+// session returns the ongoing session of the given client, or nil if there isn't one.
+// The state lock must be held when calling this.
+func (s *State) session(id enum.ClientID) *ClientSession {
+	for i := len(s.snapshot.ClientSessions) - 1; i >= 0; i-- {
+		if s.snapshot.ClientSessions[i].ClientID == id && s.snapshot.ClientSessions[i].Connected() {
+			return &s.snapshot.ClientSessions[i]
+		}
+	}
+	return nil
+}
+
+// startSession records a client joining the server.
+// The state lock must be held when calling this.
+func (s *State) startSession(id enum.ClientID, date time.Time) *ClientSession {
+	if cs := s.session(id); cs != nil {
+		return cs
+	}
+
+	s.snapshot.ClientSessions = append(s.snapshot.ClientSessions, ClientSession{
+		ClientID:   id,
+		Joined:     time.Now().UTC(),
+		JoinedDate: date,
+	})
+	s.trimSessions()
+	return s.session(id)
+}
+
+// trackSession updates the client's ongoing session with their current details, starting one if we missed them
+// joining.
+// The state lock must be held when calling this.
+func (s *State) trackSession(cli Client) {
+	cs := s.session(cli.ID)
+	if cs == nil {
+		cs = s.startSession(cli.ID, cli.JoinDate)
+	}
+
+	cs.Name = cli.Name
+	if cli.Address != nil {
+		cs.Address = append(net.IP(nil), cli.Address...)
+	}
+	if cli.Company.IsValid() {
+		if n := len(cs.Companies); n == 0 || cs.Companies[n-1] != cli.Company {
+			cs.Companies = append(cs.Companies, cli.Company)
+		}
+	}
+}
+
+// endSession records a client leaving the server, with the error that caused it (if any).
+// The state lock must be held when calling this.
+func (s *State) endSession(id enum.ClientID, reason *enum.NetError) {
+	cs := s.session(id)
+	if cs == nil {
+		return
+	}
+
+	cs.Left = time.Now().UTC()
+	cs.LeftDate = s.snapshot.DateCurrent
+	cs.QuitReason = reason
+}
+
+// trimSessions drops the oldest finished sessions beyond the session limit.
+// The state lock must be held when calling this.
+func (s *State) trimSessions() {
+	limit := s.SessionLength
+	if limit <= 0 {
+		limit = DefaultSessionLength
+	}
+
+	sessions := s.snapshot.ClientSessions
+	excess := len(sessions) - limit
+	if excess <= 0 {
+		return
+	}
+
+	// Usually the oldest sessions are finished, so they can be dropped by reslicing, as with company history
+	finished := true
+	for _, cs := range sessions[:excess] {
+		finished = finished && !cs.Connected()
+	}
+	if finished {
+		s.snapshot.ClientSessions = sessions[excess:]
+		return
+	}
+
+	// Ongoing sessions are always kept, so they can be finished later. That means moving the sessions after them
+	// down, so drop a batch of extra sessions while we're at it, to make room for the next few.
+	excess += limit / 4
+	kept := sessions[:0]
+	for _, cs := range sessions {
+		if excess > 0 && !cs.Connected() {
+			excess--
+			continue
+		}
+		kept = append(kept, cs)
+	}
+	// Let go of the sessions left beyond the end
+	for i := len(kept); i < len(sessions); i++ {
+		sessions[i] = ClientSession{}
+	}
+	s.snapshot.ClientSessions = kept
+}
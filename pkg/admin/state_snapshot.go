@@ -0,0 +1,174 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
+	"net"
+	"sort"
+)
+
+// Snapshot returns a deep copy of the state, taken under its lock.
+// The copy doesn't change as events arrive, and is safe to read (or marshal) from any goroutine.
+func (s *State) Snapshot() StateSnapshot {
+	if s == nil {
+		return StateSnapshot{}
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.snapshot.copy()
+}
+
+// MarshalJSON marshals a snapshot of the state, so that the state can be safely passed to json.Marshal.
+func (s *State) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Snapshot())
+}
+
+// Client returns the client with the given ID.
+// The second return value is false if the client isn't known.
+func (s *State) Client(id enum.ClientID) (Client, bool) {
+	if s == nil {
+		return Client{}, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	cli, ok := s.snapshot.Clients[id]
+	return cli.copy(), ok
+}
+
+// Company returns the company with the given ID.
+// The second return value is false if the company isn't known.
+func (s *State) Company(id enum.CompanyID) (Company, bool) {
+	if s == nil {
+		return Company{}, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	com, ok := s.snapshot.Companies[id]
+	return com, ok
+}
+
+// CommandName returns the name of the command with the given ID.
+// The second return value is false if the name isn't known.
+func (s *State) CommandName(id uint16) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	name, ok := s.snapshot.Commands[id]
+	return name, ok
+}
+
+// MapSize returns the size of the current map.
+func (s *State) MapSize() util.MapSize {
+	if s == nil {
+		return util.MapSize{}
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	return util.MapSize{Width: s.snapshot.MapWidth, Height: s.snapshot.MapHeight}
+}
+
+// TileXY returns the coordinates of the tile on the current map.
+// If the tile isn't on the map (or we don't know the map size yet), util.ErrInvalidTile is returned.
+func (s *State) TileXY(t util.Tile) (util.TileXY, error) {
+	return s.MapSize().XY(t)
+}
+
+// ClientsSorted returns all known clients, ordered by client ID.
+func (s *State) ClientsSorted() []Client {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	clients := make([]Client, 0, len(s.snapshot.Clients))
+	for _, c := range s.snapshot.Clients {
+		clients = append(clients, c.copy())
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ID < clients[j].ID
+	})
+	return clients
+}
+
+// CompaniesSorted returns all known companies, ordered by company ID.
+func (s *State) CompaniesSorted() []Company {
+	if s == nil {
+		return nil
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+
+	companies := make([]Company, 0, len(s.snapshot.Companies))
+	for _, c := range s.snapshot.Companies {
+		companies = append(companies, c)
+	}
+	sort.Slice(companies, func(i, j int) bool {
+		return companies[i].ID < companies[j].ID
+	})
+	return companies
+}
+
+// copy returns a deep copy of the snapshot.
+func (ss StateSnapshot) copy() StateSnapshot {
+	res := ss
+
+	res.Clients = make(map[enum.ClientID]Client, len(ss.Clients))
+	for k, v := range ss.Clients {
+		res.Clients[k] = v.copy()
+	}
+
+	res.Companies = make(map[enum.CompanyID]Company, len(ss.Companies))
+	for k, v := range ss.Companies {
+		res.Companies[k] = v
+	}
+
+	res.History = make(map[enum.CompanyID][]CompanySample, len(ss.History))
+	for k, v := range ss.History {
+		res.History[k] = append([]CompanySample(nil), v...)
+	}
+
+	res.Commands = make(map[uint16]string, len(ss.Commands))
+	for k, v := range ss.Commands {
+		res.Commands[k] = v
+	}
+
+	if ss.ConsoleLog != nil {
+		res.ConsoleLog = append([]ConsoleLine(nil), ss.ConsoleLog...)
+	}
+	if ss.ChatLog != nil {
+		res.ChatLog = append([]ChatMessage(nil), ss.ChatLog...)
+	}
+
+	if ss.ClientSessions != nil {
+		res.ClientSessions = make([]ClientSession, len(ss.ClientSessions))
+		for i, cs := range ss.ClientSessions {
+			res.ClientSessions[i] = cs.copy()
+		}
+	}
+
+	return res
+}
+
+// copy returns a deep copy of the client.
+func (cli Client) copy() Client {
+	if cli.Address != nil {
+		cli.Address = append(net.IP(nil), cli.Address...)
+	}
+	return cli
+}
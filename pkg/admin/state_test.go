@@ -0,0 +1,310 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestSession returns a disconnected session with state tracking enabled, for feeding events into.
+func newTestSession() *Session {
+	s, _ := New("localhost", 3977, "")
+	s.LogLevel = -1
+	return s
+}
+
+func TestStateSnapshot(t *testing.T) {
+	s := newTestSession()
+	s.State.OnInterface(s, &ClientInfo{ID: 5, Name: "Alice", Address: "10.0.0.1", Company: 2})
+	s.State.OnInterface(s, &ClientInfo{ID: 3, Name: "Bob", Company: enum.CompanyIDSpectator})
+	s.State.OnInterface(s, &CompanyInfo{ID: 2, Name: "Alice Transport"})
+
+	snap := s.State.Snapshot()
+
+	// Changes to the state must not leak into the snapshot
+	s.State.OnInterface(s, &ClientUpdate{ID: 5, Name: "Alice2", Company: 2})
+	s.State.OnInterface(s, &ClientQuit{ID: 3})
+	assert.Equal(t, "Alice", snap.Clients[5].Name)
+	assert.Contains(t, snap.Clients, enum.ClientID(3))
+
+	cli, ok := s.State.Client(5)
+	assert.True(t, ok)
+	assert.Equal(t, "Alice2", cli.Name)
+	cli.Address[0] = 99
+	cli, _ = s.State.Client(5)
+	assert.Equal(t, "10.0.0.1", cli.Address.String())
+
+	sorted := s.State.ClientsSorted()
+	assert.Len(t, sorted, 1)
+	assert.Equal(t, enum.ClientID(5), sorted[0].ID)
+
+	// Marshalling the state directly must be equivalent to marshalling a snapshot
+	a, err := json.Marshal(s.State)
+	assert.NoError(t, err)
+	b, err := json.Marshal(s.State.Snapshot())
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(b), string(a))
+}
+
+func TestStateHistory(t *testing.T) {
+	s := newTestSession()
+	s.State.HistoryLength = 3
+
+	for day := uint32(1); day <= 5; day++ {
+		s.State.OnInterface(s, &Date{CurrentDate: 708570 + day})
+		s.State.OnInterface(s, &CompanyEconomy{ID: 1, Money: util.Money(day * 100), ValueLastQuarter: -50})
+		s.State.OnInterface(s, &CompanyStats{ID: 1, Trains: uint16(day), Buses: 1})
+	}
+
+	// Economy and statistics on the same date are merged, and old samples are dropped
+	history := s.State.CompanyHistory(1)
+	assert.Len(t, history, 3)
+	assert.Equal(t, util.DateFormat(708573), history[0].Date)
+	assert.Equal(t, util.Money(300), history[0].Money)
+
+	money := s.State.Series(1, MetricMoney)
+	assert.Equal(t, []int64{300, 400, 500}, []int64{money[0].Value, money[1].Value, money[2].Value})
+	vehicles := s.State.Series(1, MetricVehicles)
+	assert.Equal(t, int64(6), vehicles[2].Value)
+	assert.Equal(t, int64(-50), s.State.Series(1, MetricValue)[0].Value)
+
+	// A long running game doesn't hold on to the samples it dropped
+	for day := uint32(6); day <= 1000; day++ {
+		s.State.OnInterface(s, &Date{CurrentDate: 708570 + day})
+		s.State.OnInterface(s, &CompanyEconomy{ID: 1, Money: util.Money(day * 100)})
+	}
+	history = s.State.CompanyHistory(1)
+	assert.Len(t, history, 3)
+	assert.Equal(t, util.DateFormat(709570), history[2].Date)
+	assert.True(t, cap(s.State.snapshot.History[1]) <= 8)
+
+	// Company IDs are reused, so a removed company loses its history
+	s.State.OnInterface(s, &CompanyRemove{ID: 1})
+	assert.Empty(t, s.State.CompanyHistory(1))
+}
+
+func TestStateSessions(t *testing.T) {
+	s := newTestSession()
+	s.State.OnInterface(s, &Date{CurrentDate: 708571})
+
+	// Alice was already playing when we connected, Bob joins while we watch
+	s.State.OnInterface(s, &ClientInfo{ID: 5, Name: "Alice", Address: "10.0.0.1", Company: 2, JoinDate: 708500})
+	s.State.OnInterface(s, &ClientJoin{ID: 6})
+	s.State.OnInterface(s, &ClientInfo{ID: 6, Name: "Bob", Company: enum.CompanyIDSpectator})
+	s.State.OnInterface(s, &ClientUpdate{ID: 6, Name: "Bob", Company: 1})
+	s.State.OnInterface(s, &ClientUpdate{ID: 6, Name: "Bob", Company: 3})
+
+	s.State.OnInterface(s, &Date{CurrentDate: 708572})
+	s.State.OnInterface(s, &ClientQuit{ID: 5})
+	s.State.OnInterface(s, &ClientError{ID: 6, Error: enum.NetErrorTimeoutComputer})
+
+	// Clients that error are disconnected by the server
+	clients, _, _ := s.State.Counts()
+	assert.Equal(t, 0, clients)
+
+	alice := s.State.SessionsByName("alice")
+	assert.Len(t, alice, 1)
+	assert.Equal(t, util.DateFormat(708500), alice[0].JoinedDate)
+	assert.Equal(t, util.DateFormat(708572), alice[0].LeftDate)
+	assert.Equal(t, "10.0.0.1", alice[0].Address.String())
+	assert.Equal(t, []enum.CompanyID{2}, alice[0].Companies)
+	assert.Nil(t, alice[0].QuitReason)
+
+	bob := s.State.SessionsByName("Bob")
+	assert.Len(t, bob, 1)
+	assert.Equal(t, util.DateFormat(708571), bob[0].JoinedDate)
+	assert.Equal(t, []enum.CompanyID{1, 3}, bob[0].Companies)
+	assert.Equal(t, enum.NetErrorTimeoutComputer, *bob[0].QuitReason)
+	assert.False(t, bob[0].Connected())
+
+	// Fake an hour long session for Alice
+	s.State.Lock()
+	s.State.snapshot.ClientSessions[0].Joined = s.State.snapshot.ClientSessions[0].Left.Add(-time.Hour)
+	s.State.Unlock()
+	assert.Equal(t, time.Hour, s.State.Playtime("Alice"))
+	assert.Equal(t, time.Hour, s.State.Playtimes()["Alice"])
+
+	assert.Len(t, s.State.SessionsBetween(time.Now().Add(-30*time.Minute), time.Now()), 2)
+	assert.Len(t, s.State.SessionsBetween(time.Now().Add(-3*time.Hour), time.Now().Add(-2*time.Hour)), 0)
+}
+
+func TestStateCommands(t *testing.T) {
+	s := newTestSession()
+
+	// Command names arrive over several packets: true, uint16 ID, name... false
+	s.onInterface(&CmdNames{Commands: map[uint16]string{0: "CmdBuildRailroadTrack"}})
+	names := &CmdNames{}
+	assert.NoError(t, ottdUnmarshal([]byte("\x01\x03\x00CmdBuildBridge\x00\x00"), names))
+	s.onInterface(names)
+
+	name, ok := s.State.CommandName(3)
+	assert.True(t, ok)
+	assert.Equal(t, "CmdBuildBridge", name)
+	assert.Len(t, s.State.Snapshot().Commands, 2)
+
+	// Logged commands can be named from their ID
+	r := &CmdLogging{}
+	assert.NoError(t, ottdUnmarshal([]byte("\x05\x00\x00\x00\x01\x00\x00\x01\x00\x00\x00\x02\x00\x00\x00\x03\x00\x00\x00hi\x00\x04\x00\x00\x00"), r))
+	s.onInterface(r)
+	assert.Equal(t, enum.ClientID(5), r.Client)
+	assert.Equal(t, uint32(4), r.Frame)
+	name, _ = s.State.CommandName(r.CommandID)
+	assert.Equal(t, "CmdBuildRailroadTrack", name)
+
+	// The tile only makes sense once we know the map size
+	_, err := s.State.TileXY(r.Tile)
+	assert.Equal(t, util.ErrInvalidTile, err)
+	s.State.OnInterface(s, &Welcome{MapWidth: 64, MapHeight: 64})
+	p, err := s.State.TileXY(r.Tile)
+	assert.NoError(t, err)
+	assert.Equal(t, "3,0", p.String())
+
+	// Names are forgotten when the server version changes
+	s.State.OnInterface(s, &Welcome{Version: "14.0", MapWidth: 64, MapHeight: 64})
+	_, ok = s.State.CommandName(3)
+	assert.False(t, ok)
+}
+
+func TestStateSessionLength(t *testing.T) {
+	s := newTestSession()
+	s.State.SessionLength = 4
+
+	// Alice stays the whole time, while everyone else comes and goes
+	s.State.OnInterface(s, &ClientJoin{ID: 1})
+	for id := enum.ClientID(2); id < 100; id++ {
+		s.State.OnInterface(s, &ClientJoin{ID: id})
+		s.State.OnInterface(s, &ClientQuit{ID: id})
+		assert.True(t, len(s.State.Sessions()) <= 5)
+	}
+
+	sessions := s.State.Sessions()
+	assert.Equal(t, enum.ClientID(1), sessions[0].ClientID)
+	assert.True(t, sessions[0].Connected())
+	assert.Equal(t, enum.ClientID(99), sessions[len(sessions)-1].ClientID)
+}
+
+func TestStateLogs(t *testing.T) {
+	s := newTestSession()
+	s.State.ChatLength = 2
+	start := time.Now().UTC()
+
+	s.State.OnInterface(s, &ClientInfo{ID: 5, Name: "Alice", Company: 2})
+	s.State.OnInterface(s, &CompanyInfo{ID: 2, Name: "Alice Transport"})
+	s.State.OnInterface(s, &Console{Origin: "net", Message: "Client #5 joined"})
+	s.State.OnInterface(s, &Console{Origin: "console", Message: "Game saved"})
+	s.State.OnInterface(s, &Chat{Action: enum.ActionChat, Destination: enum.DestinationBroadcast, ID: 5, Message: "first"})
+	s.State.OnInterface(s, &Chat{Action: enum.ActionChat, Destination: enum.DestinationTeam, ID: 5, Message: "Hello there"})
+	s.State.OnInterface(s, &Chat{Action: enum.ActionChat, Destination: enum.DestinationBroadcast, ID: 6, Message: "hello from 6"})
+
+	// Only the most recent messages are kept
+	chat := s.State.Chat()
+	assert.Len(t, chat, 2)
+	assert.Equal(t, "Alice", chat[0].ClientName)
+	assert.Equal(t, "Alice Transport", chat[0].CompanyName)
+	assert.Equal(t, enum.DestinationTeam, chat[0].Destination)
+	assert.Equal(t, enum.CompanyIDSpectator, chat[1].Company)
+
+	assert.Len(t, s.State.SearchChat(LogFilter{Text: "HELLO"}), 2)
+	assert.Len(t, s.State.SearchChat(LogFilter{Text: "hello", Client: 6}), 1)
+	assert.Len(t, s.State.SearchChat(LogFilter{Regexp: regexp.MustCompile(`^hello`)}), 1)
+	assert.Len(t, s.State.SearchChat(LogFilter{From: start, To: time.Now().UTC()}), 2)
+	assert.Empty(t, s.State.SearchChat(LogFilter{To: start.Add(-time.Minute)}))
+
+	assert.Len(t, s.State.Console(), 2)
+	lines := s.State.SearchConsole(LogFilter{Origin: "net", Text: "joined"})
+	assert.Len(t, lines, 1)
+	assert.Equal(t, "Client #5 joined", lines[0].Message)
+
+	// Busy servers don't make us hold on to old lines
+	s.State.ConsoleLength = 10
+	for i := 0; i < 1000; i++ {
+		s.State.OnInterface(s, &Console{Origin: "net", Message: strconv.Itoa(i)})
+	}
+	console := s.State.Console()
+	assert.Len(t, console, 10)
+	assert.Equal(t, "999", console[9].Message)
+	assert.True(t, cap(s.State.snapshot.ConsoleLog) <= 25)
+}
+
+func TestStateLeaderboard(t *testing.T) {
+	s := newTestSession()
+	s.State.OnInterface(s, &CompanyInfo{ID: 0, Name: "Alpha"})
+	s.State.OnInterface(s, &CompanyInfo{ID: 1, Name: "Beta"})
+	s.State.OnInterface(s, &CompanyInfo{ID: 2, Name: "Robot", IsAI: true})
+
+	// End of 1939, then the start of 1940
+	s.State.OnInterface(s, &Date{CurrentDate: 708569})
+	s.State.OnInterface(s, &CompanyEconomy{ID: 0, Money: 100, ValueLastQuarter: 10})
+	s.State.OnInterface(s, &CompanyEconomy{ID: 1, Money: 200, ValueLastQuarter: 20})
+	s.State.OnInterface(s, &Date{CurrentDate: 708600})
+	s.State.OnInterface(s, &CompanyEconomy{ID: 0, Money: 300, ValueLastQuarter: 30, ValuePreviousQuarter: 10})
+	s.State.OnInterface(s, &CompanyEconomy{ID: 1, Money: 250, ValueLastQuarter: 30, ValuePreviousQuarter: 20})
+	s.State.OnInterface(s, &CompanyEconomy{ID: 2, Money: 1000})
+
+	board := s.State.Leaderboard(MetricMoney, LeaderboardOptions{})
+	assert.Len(t, board, 3)
+	assert.Equal(t, "Robot", board[0].Company.Name)
+	assert.Equal(t, 0, board[0].PreviousRank)
+
+	board = s.State.Leaderboard(MetricMoney, LeaderboardOptions{ExcludeAI: true})
+	assert.Len(t, board, 2)
+	assert.Equal(t, "Alpha", board[0].Company.Name)
+	assert.Equal(t, 1, board[0].Rank)
+	assert.Equal(t, 2, board[0].PreviousRank)
+	assert.Equal(t, 1, board[0].Change)
+	assert.Equal(t, int64(100), board[0].PreviousValue)
+	assert.Equal(t, -1, board[1].Change)
+
+	// Equal values share a rank
+	board = s.State.Leaderboard(MetricValue, LeaderboardOptions{ExcludeAI: true})
+	assert.Equal(t, []int{1, 1}, []int{board[0].Rank, board[1].Rank})
+	assert.Equal(t, 1, board[0].Change)
+
+	m, ok := ParseMetric("value")
+	assert.True(t, ok)
+	assert.Equal(t, MetricValue, m)
+}
+
+func TestStateQueries(t *testing.T) {
+	s := newTestSession()
+	s.State.OnInterface(s, &ClientInfo{ID: 5, Name: "John Doe", Company: 1})
+	s.State.OnInterface(s, &ClientInfo{ID: 6, Name: "Johnny", Company: 1})
+	s.State.OnInterface(s, &ClientInfo{ID: 7, Name: "john", Company: enum.CompanyIDSpectator})
+	s.State.OnInterface(s, &ClientInfo{ID: 8, Name: "Alice", Address: "10.0.0.1", Company: enum.CompanyIDSpectator})
+	s.State.OnInterface(s, &CompanyInfo{ID: 1, Name: "Doe & Sons"})
+	s.State.OnInterface(s, &CompanyInfo{ID: 2, Name: "Empty Ltd"})
+	s.State.OnInterface(s, &CompanyInfo{ID: 3, Name: "Robot", IsAI: true})
+
+	assert.Len(t, s.State.ClientsInCompany(1), 2)
+	assert.Len(t, s.State.Spectators(), 2)
+
+	com, ok := s.State.CompanyOfClient(6)
+	assert.True(t, ok)
+	assert.Equal(t, "Doe & Sons", com.Name)
+	_, ok = s.State.CompanyOfClient(7)
+	assert.False(t, ok)
+
+	// Exact, then prefix (by ID), then fuzzy matches
+	found := s.State.FindClientsByName("JOHN")
+	assert.Equal(t, []enum.ClientID{7, 5, 6}, []enum.ClientID{found[0].ID, found[1].ID, found[2].ID})
+	found = s.State.FindClientsByName("jdoe")
+	assert.Len(t, found, 1)
+	assert.Equal(t, enum.ClientID(5), found[0].ID)
+
+	// Results are copies
+	found = s.State.FindClientsByName("alice")
+	found[0].Address[0] = 99
+	cli, _ := s.State.Client(8)
+	assert.Equal(t, "10.0.0.1", cli.Address.String())
+
+	empty := s.State.EmptyCompanies()
+	assert.Equal(t, []enum.CompanyID{2, 3}, []enum.CompanyID{empty[0].ID, empty[1].ID})
+	ai := s.State.AICompanies()
+	assert.Len(t, ai, 1)
+	assert.Equal(t, "Robot", ai[0].Name)
+}
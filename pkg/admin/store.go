@@ -0,0 +1,319 @@
+package admin
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StateStore is implemented by anything that can persist State between restarts.
+// Set Session.Store to have the session restore its state when it first connects, and save it regularly.
+type StateStore interface {
+	// Load returns the most recently saved snapshot, or ErrStateNotFound if nothing has been saved yet.
+	Load() (StateSnapshot, error)
+
+	// Save persists the given snapshot, replacing any previously saved one.
+	Save(StateSnapshot) error
+
+	// AppendHistory records a new economy sample for the given company.
+	// Samples are appended as they're updated, so a sample with the same date as the previous one supersedes it.
+	AppendHistory(id enum.CompanyID, cs CompanySample) error
+}
+
+// DefaultStoreInterval is the default interval at which the session saves its state to its Store.
+const DefaultStoreInterval = 5 * time.Minute
+
+// fileStoreVersion is the version of the file format written by FileStore.
+const fileStoreVersion = 1
+
+// DefaultHistorySize is the default size a FileStore's history file can grow to before it's rotated.
+const DefaultHistorySize = 16 << 20
+
+// FileStore is a StateStore which keeps the state in a JSON file.
+// Snapshots are written atomically to Path, and history samples are appended to Path + ".history", one JSON object
+// per line. Once the history file grows past HistorySize, it's moved to Path + ".history.1" (replacing the one
+// before) and a new one is started, so the history on disk is kept to around twice that.
+type FileStore struct {
+	// Path is the file the snapshot is saved to.
+	Path string
+
+	// HistorySize is the size in bytes the history file can grow to before it's rotated.
+	// Defaults to DefaultHistorySize, or never rotates if negative.
+	HistorySize int64
+
+	mu sync.Mutex
+}
+
+// NewFileStore returns a FileStore saving to the given path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// storedState is the on-disk format of a FileStore snapshot.
+type storedState struct {
+	Version int           `json:"version"`
+	SavedAt time.Time     `json:"saved_at"`
+	State   StateSnapshot `json:"state"`
+}
+
+// storedSample is the on-disk format of a FileStore history line.
+type storedSample struct {
+	Company enum.CompanyID `json:"company"`
+	Sample  CompanySample  `json:"sample"`
+}
+
+// Load reads the snapshot from the file.
+func (fs *FileStore) Load() (ss StateSnapshot, err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := ioutil.ReadFile(fs.Path)
+	if os.IsNotExist(err) {
+		return ss, ErrStateNotFound
+	}
+	if err != nil {
+		return ss, err
+	}
+
+	var stored storedState
+	if err = json.Unmarshal(data, &stored); err != nil {
+		return ss, err
+	}
+	if stored.Version != fileStoreVersion {
+		return ss, ErrStateVersion
+	}
+	return stored.State, nil
+}
+
+// Save atomically replaces the file with the given snapshot.
+func (fs *FileStore) Save(ss StateSnapshot) (err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := json.Marshal(storedState{
+		Version: fileStoreVersion,
+		SavedAt: time.Now().UTC(),
+		State:   ss,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it over the old one, so we never leave a half written file behind
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fs.Path)
+}
+
+// AppendHistory appends the sample to the history file.
+func (fs *FileStore) AppendHistory(id enum.CompanyID, cs CompanySample) (err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := json.Marshal(storedSample{Company: id, Sample: cs})
+	if err != nil {
+		return err
+	}
+
+	if err = fs.rotateHistory(); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(fs.Path+".history", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotateHistory moves the history file out of the way if it has grown past HistorySize.
+// The store lock must be held when calling this.
+func (fs *FileStore) rotateHistory() error {
+	limit := fs.HistorySize
+	if limit == 0 {
+		limit = DefaultHistorySize
+	}
+	if limit < 0 {
+		return nil
+	}
+
+	info, err := os.Stat(fs.Path + ".history")
+	if os.IsNotExist(err) || (err == nil && info.Size() < limit) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(fs.Path+".history", fs.Path+".history.1")
+}
+
+// History reads back the history files, oldest first.
+// Superseded samples (i.e those with the same date as the one after them) are skipped.
+func (fs *FileStore) History() (map[enum.CompanyID][]CompanySample, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	res := map[enum.CompanyID][]CompanySample{}
+	for _, path := range []string{fs.Path + ".history.1", fs.Path + ".history"} {
+		if err := readHistory(path, res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// readHistory adds the samples in the given history file to res.
+func readHistory(path string, res map[enum.CompanyID][]CompanySample) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line storedSample
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return err
+		}
+		history := res[line.Company]
+		if n := len(history); n > 0 && history[n-1].Date.Equal(line.Sample.Date) {
+			history[n-1] = line.Sample
+		} else {
+			res[line.Company] = append(history, line.Sample)
+		}
+	}
+	return scanner.Err()
+}
+
+// restoreState loads the state from the session's Store, if it has one and we haven't already done so.
+func (s *Session) restoreState() {
+	if s.Store == nil || s.restored {
+		return
+	}
+	s.restored = true
+
+	ss, err := s.Store.Load()
+	if err == ErrStateNotFound {
+		s.log(LogInformational, "no stored state to restore")
+		return
+	}
+	if err != nil {
+		s.log(LogWarning, "error loading stored state, %s", err)
+		return
+	}
+
+	s.log(LogInformational, "restoring stored state")
+	s.State.Restore(ss)
+}
+
+// saveState saves a snapshot of the state to the session's Store, if it has one.
+func (s *Session) saveState() {
+	if s.Store == nil || !s.StateEnabled {
+		return
+	}
+	s.saveSnapshot(s.State.Snapshot())
+}
+
+// saveSnapshot passes the snapshot to the session's Store.
+func (s *Session) saveSnapshot(ss StateSnapshot) {
+	if err := s.Store.Save(ss); err != nil {
+		s.log(LogWarning, "error saving state, %s", err)
+	}
+}
+
+// persistState regularly saves the state to the session's Store until the listening channel is closed.
+func (s *Session) persistState(listening <-chan interface{}) {
+	interval := s.StoreInterval
+	if interval <= 0 {
+		interval = DefaultStoreInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flushHistory()
+			s.saveState()
+		case <-listening:
+			return
+		}
+	}
+}
+
+// queueHistory queues the latest history sample for the company to be passed to the session's Store (if it has
+// one) by flushHistory, so that events aren't held up by the store.
+func (s *Session) queueHistory(id enum.CompanyID) {
+	if s.Store == nil || !s.StateEnabled {
+		return
+	}
+	history := s.State.CompanyHistory(id)
+	if len(history) == 0 {
+		return
+	}
+	latest := storedSample{Company: id, Sample: history[len(history)-1]}
+
+	s.historyQueueMu.Lock()
+	defer s.historyQueueMu.Unlock()
+
+	// The economy and stats of a company usually arrive together, and make for the same sample
+	if n := len(s.historyQueue); n > 0 {
+		last := s.historyQueue[n-1]
+		if last.Company == id && last.Sample.Date.Equal(latest.Sample.Date) {
+			s.historyQueue[n-1] = latest
+			return
+		}
+	}
+	s.historyQueue = append(s.historyQueue, latest)
+}
+
+// flushHistory passes the queued history samples to the session's Store.
+// If the store fails, the samples it didn't take are put back in the queue, to be retried next time.
+func (s *Session) flushHistory() {
+	s.historyQueueMu.Lock()
+	queue := s.historyQueue
+	s.historyQueue = nil
+	s.historyQueueMu.Unlock()
+
+	for i, q := range queue {
+		if err := s.Store.AppendHistory(q.Company, q.Sample); err != nil {
+			s.log(LogWarning, "error appending history for company %d, %s", q.Company, err)
+
+			s.historyQueueMu.Lock()
+			s.historyQueue = append(append([]storedSample(nil), queue[i:]...), s.historyQueue...)
+			s.historyQueueMu.Unlock()
+			return
+		}
+	}
+}
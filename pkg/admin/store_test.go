@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"errors"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopenttd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fs := NewFileStore(filepath.Join(dir, "state.json"))
+	_, err = fs.Load()
+	assert.Equal(t, ErrStateNotFound, err)
+
+	s := newTestSession()
+	s.State.OnInterface(s, &Date{CurrentDate: 708571})
+	s.State.OnInterface(s, &ClientInfo{ID: 5, Name: "Alice", Address: "10.0.0.1", Company: 2})
+	s.State.OnInterface(s, &CompanyInfo{ID: 2, Name: "Alice Transport"})
+	s.State.OnInterface(s, &CompanyEconomy{ID: 2, Money: 1000})
+
+	assert.NoError(t, fs.Save(s.State.Snapshot()))
+	ss, err := fs.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice", ss.Clients[5].Name)
+	assert.Equal(t, "10.0.0.1", ss.Clients[5].Address.String())
+	assert.Equal(t, "Alice Transport", ss.Companies[2].Name)
+	assert.Equal(t, util.Money(1000), ss.History[2][0].Money)
+
+	// Later samples on the same date supersede earlier ones
+	assert.NoError(t, fs.AppendHistory(2, CompanySample{Date: util.DateFormat(708571), Money: 1}))
+	assert.NoError(t, fs.AppendHistory(2, CompanySample{Date: util.DateFormat(708571), Money: 2}))
+	assert.NoError(t, fs.AppendHistory(2, CompanySample{Date: util.DateFormat(708572), Money: 3}))
+	history, err := fs.History()
+	assert.NoError(t, err)
+	assert.Len(t, history[2], 2)
+	assert.Equal(t, util.Money(2), history[2][0].Money)
+
+	// The history file is rotated once it gets too big, but read back in full
+	fs.HistorySize = 1
+	assert.NoError(t, fs.AppendHistory(2, CompanySample{Date: util.DateFormat(708573), Money: 4}))
+	_, err = os.Stat(fs.Path + ".history.1")
+	assert.NoError(t, err)
+	history, err = fs.History()
+	assert.NoError(t, err)
+	assert.Len(t, history[2], 3)
+	assert.Equal(t, util.Money(4), history[2][2].Money)
+
+	// Files from other versions are refused
+	assert.NoError(t, ioutil.WriteFile(fs.Path, []byte(`{"version":99}`), 0644))
+	_, err = fs.Load()
+	assert.Equal(t, ErrStateVersion, err)
+}
+
+func TestStoreHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopenttd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newTestSession()
+	fs := NewFileStore(filepath.Join(dir, "state.json"))
+	s.Store = fs
+
+	// Samples are only written when flushed, and updates on the same date make one sample
+	s.State.OnInterface(s, &Date{CurrentDate: 708571})
+	s.onInterface(&CompanyEconomy{ID: 2, Money: 1000})
+	s.onInterface(&CompanyStats{ID: 2, Trains: 3})
+	s.State.OnInterface(s, &Date{CurrentDate: 708572})
+	s.onInterface(&CompanyEconomy{ID: 2, Money: 2000})
+	history, err := fs.History()
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	assert.Len(t, s.historyQueue, 2)
+
+	s.flushHistory()
+	history, err = fs.History()
+	assert.NoError(t, err)
+	assert.Len(t, history[2], 2)
+	assert.Equal(t, uint16(3), history[2][0].Vehicles.Train)
+	assert.Equal(t, util.Money(2000), history[2][1].Money)
+	assert.Empty(t, s.historyQueue)
+}
+
+// failingStore is a FileStore whose next AppendHistory fails.
+type failingStore struct {
+	*FileStore
+	fail bool
+}
+
+func (fs *failingStore) AppendHistory(id enum.CompanyID, cs CompanySample) error {
+	if fs.fail {
+		fs.fail = false
+		return errors.New("disk full")
+	}
+	return fs.FileStore.AppendHistory(id, cs)
+}
+
+func TestStoreHistoryRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopenttd")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s := newTestSession()
+	fs := &failingStore{FileStore: NewFileStore(filepath.Join(dir, "state.json")), fail: true}
+	s.Store = fs
+
+	for day := uint32(1); day <= 3; day++ {
+		s.State.OnInterface(s, &Date{CurrentDate: 708570 + day})
+		s.onInterface(&CompanyEconomy{ID: 2, Money: util.Money(day)})
+	}
+
+	// Nothing is lost when the store fails, it's just written later
+	s.flushHistory()
+	history, err := fs.History()
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	assert.Len(t, s.historyQueue, 3)
+
+	s.State.OnInterface(s, &Date{CurrentDate: 708574})
+	s.onInterface(&CompanyEconomy{ID: 2, Money: 4})
+	s.flushHistory()
+	history, err = fs.History()
+	assert.NoError(t, err)
+	if assert.Len(t, history[2], 4) {
+		for i, cs := range history[2] {
+			assert.Equal(t, util.Money(i+1), cs.Money)
+		}
+	}
+	assert.Empty(t, s.historyQueue)
+}
+
+func TestStateReconcile(t *testing.T) {
+	s := newTestSession()
+	s.State.Restore(StateSnapshot{
+		Clients: map[enum.ClientID]Client{
+			3: {ID: 3, Name: "Gone"},
+			5: {ID: 5, Name: "Alice"},
+		},
+		Companies: map[enum.CompanyID]Company{
+			0: {ID: 0, Name: "Dissolved"},
+			2: {ID: 2, Name: "Alice Transport"},
+		},
+	})
+
+	// Only what the server tells us about after polling is kept
+	s.State.markUnconfirmed()
+	s.State.OnInterface(s, &ClientInfo{ID: 5, Name: "Alice"})
+	s.State.OnInterface(s, &CompanyInfo{ID: 2, Name: "Alice Transport"})
+	s.State.pruneUnconfirmed(s)
+
+	ss := s.State.Snapshot()
+	assert.Len(t, ss.Clients, 1)
+	assert.Contains(t, ss.Clients, enum.ClientID(5))
+	assert.Len(t, ss.Companies, 1)
+	assert.Contains(t, ss.Companies, enum.CompanyID(2))
+}
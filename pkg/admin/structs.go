@@ -40,6 +40,31 @@ type Session struct {
 	// e.g false = launch event handlers in their own goroutines.
 	SyncEvents bool
 
+	// Minimum interval between outgoing chat messages, so bots don't flood players.
+	// Set to zero to disable chat rate limiting.
+	ChatRateLimit time.Duration
+
+	// Number of chat messages that may be sent at once before ChatRateLimit applies.
+	ChatBurst int
+
+	// How long to wait for the server to finish an RCON command before giving up on it.
+	RconTimeout time.Duration
+
+	// Where to persist the State between restarts, if anywhere.
+	// The state is restored from the store the first time the session connects.
+	Store StateStore
+
+	// How often to save the State to Store. Defaults to DefaultStoreInterval.
+	StoreInterval time.Duration
+
+	// Backups taken with Backup are named BackupPrefix followed by the time they were taken, formatted with
+	// BackupLayout (as in time.Format). Defaults to DefaultBackupPrefix and DefaultBackupLayout.
+	BackupPrefix string
+	BackupLayout string
+
+	// How many backups to keep; older ones are deleted. Defaults to DefaultBackupKeep, or keeps all if negative.
+	BackupKeep int
+
 	// Exposed but should not be modified by User.
 
 	// Whether the connection is ready
@@ -49,8 +74,10 @@ type Session struct {
 	// StateEnabled is true.
 	State *State
 
-	// Cache of requested update frequencies - these will be
-	// automatically sent to the server if reconnection is required.
+	// Update frequencies to request from the server.
+	// Deprecated: use RequestUpdates, and Subscriptions to see what the server made of them. This is only read once,
+	// when the session first connects, so set it before calling Open and don't touch it afterwards. It no longer
+	// records the subscriptions made with RequestUpdates.
 	UpdateFrequencies map[enum.UpdateType]enum.UpdateFrequency
 
 	// The user agent
@@ -71,22 +98,41 @@ type Session struct {
 	conn *net.TCPConn
 
 	// Acceptable polling rates
-	pollrates map[enum.UpdateType]uint16
+	pollrates   map[enum.UpdateType]uint16
+	pollratesMu sync.RWMutex
+
+	// Requested update subscriptions - these will be
+	// automatically sent to the server if reconnection is required.
+	subscriptions subscriptionManager
 
 	// Pending RCON commands
 	rconQueue chan *rconRequest
 
-	// Channel for RCON responses to be sent to
-	rconChan chan *rconResp
+	// The RCON command the server is currently running for us, if any
+	rconMu      sync.Mutex
+	rconCurrent *rconRequest
 
 	// When nil, the session is not listening.
 	listening chan interface{}
 
 	// used to make sure writes do not happen concurrently
 	connMutex sync.Mutex
+
+	// Outgoing chat rate limiting, and making sure split messages are sent together
+	chatLimiter chatLimiter
+	chatMutex   sync.Mutex
+
+	// Whether the state has been restored from Store yet
+	restored bool
+
+	// History samples waiting to be passed to Store, see queueHistory
+	historyQueue   []storedSample
+	historyQueueMu sync.Mutex
 }
 
 type Company struct {
+	// The ID of the company.
+	ID enum.CompanyID `json:"id"`
 	// The company name.
 	Name string `json:"name"`
 	// The manager of the company (client-defined, not definitively the name of a client - it appears under the portrait).
@@ -95,14 +141,14 @@ type Company struct {
 	Colour helpers.OpenttdColour `json:"colour"`
 	// The year the company was first founded.
 	YearStart uint32 `json:"start_year"`
-	// The value of the company, in credits.
-	Value uint64 `json:"value"`
-	// The amount of disposable cash the company has.
-	Money uint64 `json:"cash"`
+	// The value of the company.
+	Value util.Money `json:"value"`
+	// The amount of disposable cash the company has. Can go negative if they're in debt.
+	Money util.Money `json:"cash"`
 	// The company's current income. Can go negative if they're making a loss.
-	Income int64 `json:"income"`
+	Income util.Money `json:"income"`
 	// The company's current loan.
-	Loan uint64 `json:"loan"`
+	Loan util.Money `json:"loan"`
 	// The number of quarters this company has been in a bankruptcy state (this may mean they're about to be dissolved!)
 	Bankruptcy uint8 `json:"bankruptcy"`
 
@@ -123,9 +169,9 @@ type Company struct {
 	// Cargo delivered in the quarter before the last full one.
 	CargoPreviousQuarter uint16 `json:"cargo_previous"`
 	// Company value in the last quarter.
-	ValueLastQuarter uint64 `json:"value_last"`
+	ValueLastQuarter util.Money `json:"value_last"`
 	// Company value in the previous quarter.
-	ValuePreviousQuarter uint64 `json:"value_previous"`
+	ValuePreviousQuarter util.Money `json:"value_previous"`
 	// Company performance index in the last quarter, out of 1000.
 	PerformanceLastQuarter uint16 `json:"performance_last"`
 	// Company performance index in the previous quarter.
@@ -142,6 +188,8 @@ type Company struct {
 }
 
 type Client struct {
+	// The ID of the client.
+	ID enum.ClientID `json:"id"`
 	// The client nickname.
 	Name string `json:"name"`
 	// The client's IP address.
@@ -152,6 +200,6 @@ type Client struct {
 	// The date the client joined the game.
 	// This is set to zero if the client is the server host, so watch out!
 	JoinDate time.Time `json:"date_join"`
-	// The ID of the company that the client is playing in (set to 255 for spectators)
-	Company uint8 `json:"company"`
+	// The ID of the company that the client is playing in (see CompanyID.IsSpectator())
+	Company enum.CompanyID `json:"company"`
 }
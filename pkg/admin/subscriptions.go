@@ -0,0 +1,285 @@
+package admin
+
+import (
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/ropenttd/gopenttd/pkg/admin/packets"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Update subscriptions are dealt with in this file to help keep things a little tidier.
+
+// SubscriptionStatus describes what the server made of a requested update subscription.
+type SubscriptionStatus uint8
+
+const (
+	// SubscriptionPending means we haven't told the server about the subscription yet (i.e we're not connected).
+	SubscriptionPending SubscriptionStatus = iota
+	// SubscriptionAccepted means the server supports the update type at the requested frequency.
+	SubscriptionAccepted
+	// SubscriptionDowngraded means the server doesn't support the requested frequency, so the nearest one it does
+	// support was requested instead.
+	SubscriptionDowngraded
+	// SubscriptionPolled means the server only supports polling for the update type,
+	// so gopenttd polls for it on a schedule approximating the requested frequency.
+	SubscriptionPolled
+	// SubscriptionRejected means the server doesn't support the update type at all.
+	SubscriptionRejected
+)
+
+// String returns the string representation of the SubscriptionStatus.
+func (st SubscriptionStatus) String() string {
+	names := [...]string{
+		"Pending",
+		"Accepted",
+		"Downgraded",
+		"Polled",
+		"Rejected",
+	}
+	// prevent panics for out of range lookups
+	if st > SubscriptionRejected {
+		return "Unknown"
+	}
+	return names[st]
+}
+
+// SubscriptionResult is the outcome of reconciling a requested update subscription against the server's capabilities.
+type SubscriptionResult struct {
+	// Type is the subscribed update type.
+	Type enum.UpdateType `json:"type"`
+	// Wanted is the frequency that was requested with RequestUpdates.
+	Wanted enum.UpdateFrequency `json:"wanted"`
+	// Actual is the frequency that updates will actually arrive at.
+	Actual enum.UpdateFrequency `json:"actual"`
+	// Status describes how Actual was arrived at.
+	Status SubscriptionStatus `json:"status"`
+}
+
+// gameDay is how long an in-game day lasts in real time (74 ticks of 30ms each).
+// Fallback polling uses this to approximate the game's own update frequencies.
+const gameDay = 74 * 30 * time.Millisecond
+
+// pollIntervals are the real time intervals used to emulate each update frequency by polling.
+var pollIntervals = map[enum.UpdateFrequency]time.Duration{
+	enum.UpdateFrequencyDaily:         gameDay,
+	enum.UpdateFrequencyWeekly:        7 * gameDay,
+	enum.UpdateFrequencyMonthly:       30 * gameDay,
+	enum.UpdateFrequencyQuarterly:     91 * gameDay,
+	enum.UpdateFrequencyAnnually:      365 * gameDay,
+	enum.UpdateFrequencyAutomatically: gameDay,
+}
+
+// subscriptionManager keeps track of the update subscriptions the user wants, and what the server made of them.
+type subscriptionManager struct {
+	sync.Mutex
+	wanted  map[enum.UpdateType]enum.UpdateFrequency
+	results map[enum.UpdateType]SubscriptionResult
+	// Closing one of these stops the fallback poller for that update type.
+	pollers map[enum.UpdateType]chan struct{}
+	// The listening channel of the current connection, which closes when we disconnect.
+	listening <-chan interface{}
+	// Whether the subscriptions in Session.UpdateFrequencies have been picked up yet.
+	seeded bool
+}
+
+// RequestUpdates subscribes to updates of the given type from the server at a given interval.
+// The subscription is remembered, and reconciled against the server's capabilities every time we (re)connect:
+// unsupported frequencies are downgraded to the nearest supported one, and update types that can only be polled
+// for are polled automatically. Use Subscriptions to find out what the server made of it.
+// If we're not connected yet, the subscription is sent once we are.
+// Supplying a frequency of POLL, or an update type the server doesn't support, returns an error - use Session.Poll()
+func (s *Session) RequestUpdates(t enum.UpdateType, f enum.UpdateFrequency) (err error) {
+	if f == enum.UpdateFrequencyPoll {
+		return ErrInvalidUpdateFrequency
+	}
+
+	s.subscriptions.Lock()
+	defer s.subscriptions.Unlock()
+
+	if s.subscriptions.wanted == nil {
+		s.subscriptions.wanted = map[enum.UpdateType]enum.UpdateFrequency{}
+	}
+	s.subscriptions.wanted[t] = f
+
+	s.pollratesMu.RLock()
+	connected := s.pollrates != nil
+	s.pollratesMu.RUnlock()
+	if !connected {
+		s.subscriptions.setResult(SubscriptionResult{Type: t, Wanted: f, Status: SubscriptionPending})
+		return nil
+	}
+
+	res, err := s.reconcileSubscription(t, f)
+	if err != nil {
+		return err
+	}
+	if res.Status == SubscriptionRejected {
+		return ErrInvalidUpdateFrequency
+	}
+	return nil
+}
+
+// Subscriptions returns the current state of all requested update subscriptions, ordered by update type.
+func (s *Session) Subscriptions() (res []SubscriptionResult) {
+	s.subscriptions.Lock()
+	defer s.subscriptions.Unlock()
+
+	for _, r := range s.subscriptions.results {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Type < res[j].Type
+	})
+	return res
+}
+
+// reconcileSubscriptions sends all wanted subscriptions to the server, and emits a Subscribed event with the results.
+// This must be called after the Protocol packet has been received, with the channel that closes when we disconnect.
+func (s *Session) reconcileSubscriptions(listening <-chan interface{}) {
+	s.subscriptions.Lock()
+	s.subscriptions.listening = listening
+	// The deprecated UpdateFrequencies are read once, the first time we connect, and never touched again
+	if !s.subscriptions.seeded {
+		s.subscriptions.seeded = true
+		for t, f := range s.UpdateFrequencies {
+			if _, ok := s.subscriptions.wanted[t]; ok || f == enum.UpdateFrequencyPoll {
+				continue
+			}
+			if s.subscriptions.wanted == nil {
+				s.subscriptions.wanted = map[enum.UpdateType]enum.UpdateFrequency{}
+			}
+			s.subscriptions.wanted[t] = f
+		}
+	}
+	var results []SubscriptionResult
+	for t, f := range s.subscriptions.wanted {
+		res, err := s.reconcileSubscription(t, f)
+		if err != nil {
+			s.log(LogWarning, "error requesting %s updates, %s", t, err)
+		}
+		results = append(results, res)
+	}
+	s.subscriptions.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Type < results[j].Type
+	})
+	s.handleEvent(subscribedEventType, &Subscribed{Results: results})
+}
+
+// reconcileSubscription works out the best way of getting updates of the given type at the given frequency, and
+// asks the server for them.
+// The subscriptions lock must be held when calling this.
+func (s *Session) reconcileSubscription(t enum.UpdateType, f enum.UpdateFrequency) (res SubscriptionResult, err error) {
+	res = SubscriptionResult{Type: t, Wanted: f, Actual: f, Status: SubscriptionAccepted}
+
+	s.pollratesMu.RLock()
+	supported := enum.UpdateFrequency(s.pollrates[t])
+	s.pollratesMu.RUnlock()
+
+	// A fresh subscription replaces any fallback polling
+	if stop, ok := s.subscriptions.pollers[t]; ok {
+		close(stop)
+		delete(s.subscriptions.pollers, t)
+	}
+
+	switch {
+	case supported&f != 0:
+		// All good
+	case supported&^enum.UpdateFrequencyPoll != 0:
+		res.Actual = nearestFrequency(supported, f)
+		res.Status = SubscriptionDowngraded
+	case supported&enum.UpdateFrequencyPoll != 0:
+		res.Actual = enum.UpdateFrequencyPoll
+		res.Status = SubscriptionPolled
+	default:
+		res.Actual = 0
+		res.Status = SubscriptionRejected
+	}
+	s.subscriptions.setResult(res)
+
+	switch res.Status {
+	case SubscriptionAccepted, SubscriptionDowngraded:
+		s.log(LogInformational, "requesting %s updates %s (wanted %s)", t, res.Actual, f)
+		data := packets.AdminUpdateFrequency{
+			Type:      t,
+			Frequency: res.Actual,
+		}
+		s.connMutex.Lock()
+		err = writePacketToTcpConn(s.conn, data)
+		s.connMutex.Unlock()
+		if err == nil && t == enum.UpdateTypeCmdLogging {
+			// Command logs only carry IDs, so fetch the names to go with them
+			err = s.Poll(enum.UpdateTypeCmdNames, 0)
+		}
+	case SubscriptionPolled:
+		s.log(LogInformational, "server only supports polling for %s updates, polling every %s", t, pollIntervals[f])
+		stop := make(chan struct{})
+		if s.subscriptions.pollers == nil {
+			s.subscriptions.pollers = map[enum.UpdateType]chan struct{}{}
+		}
+		s.subscriptions.pollers[t] = stop
+		go s.pollUpdates(t, pollIntervals[f], stop, s.subscriptions.listening)
+	case SubscriptionRejected:
+		s.log(LogWarning, "server does not support %s updates", t)
+	}
+	return res, err
+}
+
+// setResult records the result of a subscription.
+// The subscriptions lock must be held when calling this.
+func (m *subscriptionManager) setResult(res SubscriptionResult) {
+	if m.results == nil {
+		m.results = map[enum.UpdateType]SubscriptionResult{}
+	}
+	m.results[res.Type] = res
+}
+
+// nearestFrequency returns the supported (non-poll) frequency closest to the wanted one,
+// preferring less frequent updates to more frequent ones.
+func nearestFrequency(supported enum.UpdateFrequency, wanted enum.UpdateFrequency) enum.UpdateFrequency {
+	if wanted == enum.UpdateFrequencyAutomatically {
+		// Automatic updates are as frequent as it gets, so go for the most frequent we can have
+		wanted = enum.UpdateFrequencyDaily
+	}
+	// Look for less frequent updates first...
+	for f := wanted; f < enum.UpdateFrequencyAutomatically; f <<= 1 {
+		if supported&f != 0 {
+			return f
+		}
+	}
+	// ... then more frequent ones...
+	for f := wanted >> 1; f > enum.UpdateFrequencyPoll; f >>= 1 {
+		if supported&f != 0 {
+			return f
+		}
+	}
+	// ... and finally, whatever the server decides.
+	return enum.UpdateFrequencyAutomatically
+}
+
+// pollUpdates polls for the given update type at a regular interval, for servers that don't support
+// subscribing to it. It stops when stop or listening is closed.
+func (s *Session) pollUpdates(t enum.UpdateType, interval time.Duration, stop <-chan struct{}, listening <-chan interface{}) {
+	if interval <= 0 {
+		interval = gameDay
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Poll(t, PollAll); err != nil {
+			s.log(LogWarning, "error polling for %s updates, %s", t, err)
+		}
+
+		select {
+		case <-ticker.C:
+			// continue loop and poll again
+		case <-stop:
+			return
+		case <-listening:
+			return
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package admin
+
+import (
+	"encoding/binary"
+	"github.com/ropenttd/gopenttd/internal/openttd_packets_admin"
+	"github.com/ropenttd/gopenttd/pkg/admin/enum"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNearestFrequency(t *testing.T) {
+	tests := []struct {
+		supported enum.UpdateFrequency
+		wanted    enum.UpdateFrequency
+		want      enum.UpdateFrequency
+	}{
+		// Less frequent updates are preferred...
+		{enum.UpdateFrequencyDaily | enum.UpdateFrequencyMonthly, enum.UpdateFrequencyWeekly, enum.UpdateFrequencyMonthly},
+		{enum.UpdateFrequencyDaily | enum.UpdateFrequencyAnnually, enum.UpdateFrequencyWeekly, enum.UpdateFrequencyAnnually},
+		// ... to more frequent ones
+		{enum.UpdateFrequencyDaily | enum.UpdateFrequencyWeekly, enum.UpdateFrequencyQuarterly, enum.UpdateFrequencyWeekly},
+		{enum.UpdateFrequencyPoll | enum.UpdateFrequencyDaily, enum.UpdateFrequencyAnnually, enum.UpdateFrequencyDaily},
+		// Automatic updates are treated as the most frequent
+		{enum.UpdateFrequencyWeekly | enum.UpdateFrequencyAnnually, enum.UpdateFrequencyAutomatically, enum.UpdateFrequencyWeekly},
+		// If nothing else will do, leave it to the server
+		{enum.UpdateFrequencyPoll | enum.UpdateFrequencyAutomatically, enum.UpdateFrequencyDaily, enum.UpdateFrequencyAutomatically},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, nearestFrequency(tt.supported, tt.wanted), "%s from %s", tt.wanted, tt.supported)
+	}
+}
+
+func TestReconcileSubscription(t *testing.T) {
+	poll := enum.UpdateFrequencyPoll
+	tests := []struct {
+		name      string
+		supported enum.UpdateFrequency
+		wanted    enum.UpdateFrequency
+		status    SubscriptionStatus
+		actual    enum.UpdateFrequency
+		// The packet we expect to be sent, if any
+		packet []byte
+	}{
+		{
+			name:      "accepted",
+			supported: enum.UpdateFrequencyDaily | enum.UpdateFrequencyWeekly,
+			wanted:    enum.UpdateFrequencyWeekly,
+			status:    SubscriptionAccepted,
+			actual:    enum.UpdateFrequencyWeekly,
+			packet:    updateFrequencyPacket(enum.UpdateTypeDate, enum.UpdateFrequencyWeekly),
+		},
+		{
+			name:      "downgraded",
+			supported: poll | enum.UpdateFrequencyMonthly,
+			wanted:    enum.UpdateFrequencyWeekly,
+			status:    SubscriptionDowngraded,
+			actual:    enum.UpdateFrequencyMonthly,
+			packet:    updateFrequencyPacket(enum.UpdateTypeDate, enum.UpdateFrequencyMonthly),
+		},
+		{
+			name:      "polled",
+			supported: poll,
+			wanted:    enum.UpdateFrequencyDaily,
+			status:    SubscriptionPolled,
+			actual:    poll,
+			packet:    pollPacket(enum.UpdateTypeDate, PollAll),
+		},
+		{
+			name:   "rejected",
+			wanted: enum.UpdateFrequencyDaily,
+			status: SubscriptionRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSession()
+			sent := newCapturingConnection(t, s)
+			listening := make(chan interface{})
+			defer close(listening)
+			defer s.conn.Close()
+			s.pollrates = map[enum.UpdateType]uint16{enum.UpdateTypeDate: uint16(tt.supported)}
+			s.subscriptions.listening = listening
+
+			s.subscriptions.Lock()
+			res, err := s.reconcileSubscription(enum.UpdateTypeDate, tt.wanted)
+			s.subscriptions.Unlock()
+			assert.NoError(t, err)
+			assert.Equal(t, SubscriptionResult{
+				Type:   enum.UpdateTypeDate,
+				Wanted: tt.wanted,
+				Actual: tt.actual,
+				Status: tt.status,
+			}, res)
+			assert.Equal(t, []SubscriptionResult{res}, s.Subscriptions())
+
+			if tt.packet != nil {
+				assert.Equal(t, tt.packet, <-sent)
+			}
+			_, polling := s.subscriptions.pollers[enum.UpdateTypeDate]
+			assert.Equal(t, tt.status == SubscriptionPolled, polling)
+		})
+	}
+}
+
+// updateFrequencyPacket returns the packet RequestUpdates sends for the given update type and frequency.
+func updateFrequencyPacket(ut enum.UpdateType, f enum.UpdateFrequency) []byte {
+	packet := []byte{byte(openttd_packets_admin.PacketAdminUpdateFrequency), 0, 0, 0, 0}
+	binary.LittleEndian.PutUint16(packet[1:], uint16(ut))
+	binary.LittleEndian.PutUint16(packet[3:], uint16(f))
+	return packet
+}
+
+func TestSubscribedKeepsOnceHandlers(t *testing.T) {
+	s := newTestSession()
+	s.SyncEvents = true
+
+	connected := false
+	s.AddHandlerOnce(func(_ *Session, _ *Connect) {
+		connected = true
+	})
+	s.reconcileSubscriptions(nil)
+	s.handleEvent(connectEventType, &Connect{})
+	assert.True(t, connected)
+}
+
+func TestUpdateFrequencies(t *testing.T) {
+	s := newTestSession()
+	s.SyncEvents = true
+	s.UpdateFrequencies[enum.UpdateTypeChat] = enum.UpdateFrequencyAutomatically
+	assert.NoError(t, s.RequestUpdates(enum.UpdateTypeDate, enum.UpdateFrequencyDaily))
+	assert.Len(t, s.UpdateFrequencies, 1)
+
+	// Entries set before connecting are picked up when we do
+	var subscribed []SubscriptionResult
+	s.AddHandler(func(_ *Session, e *Subscribed) {
+		subscribed = e.Results
+	})
+	s.reconcileSubscriptions(nil)
+	assert.Len(t, subscribed, 2)
+	assert.Equal(t, enum.UpdateTypeDate, subscribed[0].Type)
+	assert.Equal(t, enum.UpdateTypeChat, subscribed[1].Type)
+
+	// But only then
+	s.UpdateFrequencies[enum.UpdateTypeConsole] = enum.UpdateFrequencyAutomatically
+	s.reconcileSubscriptions(nil)
+	assert.Len(t, subscribed, 2)
+}
@@ -109,14 +109,23 @@ func constCase(name string) string {
 
 func isOpenttdEvent(name string) bool {
 	switch {
-	case name == "Connect", name == "Disconnect", name == "Event", name == "RateLimit", name == "Interface":
+	case name == "Connect", name == "Disconnect", name == "Event", name == "RateLimit", name == "Interface", name == "Subscribed":
 		return false
 	default:
 		return true
 	}
 }
 
+// internalEventTypes gives synthetic events their own event type, where they must not be confused with the others
+// (which share type 254) - e.g a once handler for Connect must not be used up by a Subscribed event.
+var internalEventTypes = map[string]int{
+	"Subscribed": 253,
+}
+
 func packetIndex(name string) string {
+	if t, ok := internalEventTypes[name]; ok {
+		return fmt.Sprintf("%d // internal handler", t)
+	}
 	if !isOpenttdEvent(name) {
 		return "254 // internal handler"
 	}
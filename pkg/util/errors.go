@@ -6,3 +6,4 @@ var ErrAuthentication = errors.New("authentication failed")
 var ErrBadWrite = errors.New("writing to admin port cut short")
 var ErrInvalidIncomingPacket = errors.New("received an invalid packet from the server")
 var ErrNotConnected = errors.New("not connected to server")
+var ErrInvalidTile = errors.New("tile is not on the map")
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of in-game money, in OpenTTD's base currency (Pounds Sterling).
+// OpenTTD money is signed, so companies in debt will have a negative balance.
+type Money int64
+
+// Currency describes how an in-game currency is converted and displayed.
+type Currency struct {
+	// Code is the ISO 4217 (or ISO-like) code of the currency, e.g "GBP".
+	Code string
+	// Rate is the exchange rate of the currency against one Pound Sterling.
+	Rate int64
+	// Separator is the thousands separator.
+	Separator string
+	// Prefix is printed before the amount, e.g "£".
+	Prefix string
+	// Suffix is printed after the amount, e.g " kr".
+	Suffix string
+}
+
+// Currencies are the built-in OpenTTD currencies, in the same order as the game's currency setting.
+// Exchange rates are taken from OpenTTD's currency.cpp.
+// Currencies that OpenTTD leaves to the language default use the English thousands separator.
+var Currencies = [...]Currency{
+	{Code: "GBP", Rate: 1, Separator: ",", Prefix: "£"},
+	{Code: "USD", Rate: 2, Separator: ",", Prefix: "$"},
+	{Code: "EUR", Rate: 2, Separator: ",", Prefix: "€"},
+	{Code: "JPY", Rate: 220, Separator: ",", Prefix: "¥"},
+	{Code: "ATS", Rate: 27, Separator: ",", Suffix: " S."},
+	{Code: "BEF", Rate: 81, Separator: ",", Prefix: "BEF "},
+	{Code: "CHF", Rate: 2, Separator: ",", Prefix: "CHF "},
+	{Code: "CZK", Rate: 41, Separator: ",", Suffix: " Kč"},
+	{Code: "DEM", Rate: 4, Separator: ",", Prefix: "DM "},
+	{Code: "DKK", Rate: 11, Separator: ",", Suffix: " kr"},
+	{Code: "ESP", Rate: 333, Separator: ",", Prefix: "Pts "},
+	{Code: "FIM", Rate: 12, Separator: ",", Suffix: " mk"},
+	{Code: "FRF", Rate: 10, Separator: ",", Prefix: "FF "},
+	{Code: "GRD", Rate: 500, Separator: ",", Suffix: " Dr."},
+	{Code: "HUF", Rate: 378, Separator: ",", Suffix: " Ft"},
+	{Code: "ISK", Rate: 130, Separator: ",", Suffix: " Kr"},
+	{Code: "ITL", Rate: 2850, Separator: ",", Suffix: " L."},
+	{Code: "NLG", Rate: 3, Separator: ",", Prefix: "NLG "},
+	{Code: "NOK", Rate: 12, Separator: ",", Suffix: " Kr"},
+	{Code: "PLN", Rate: 6, Separator: ",", Suffix: " zł"},
+	{Code: "RON", Rate: 5, Separator: ",", Suffix: " Lei"},
+	{Code: "RUR", Rate: 50, Separator: ",", Suffix: " p"},
+	{Code: "SIT", Rate: 479, Separator: ",", Suffix: " SIT"},
+	{Code: "SEK", Rate: 13, Separator: ",", Suffix: " Kr"},
+	{Code: "TRY", Rate: 3, Separator: ",", Suffix: " TL"},
+	{Code: "SKK", Rate: 60, Separator: ",", Suffix: " Sk"},
+	{Code: "BRL", Rate: 4, Separator: ",", Prefix: "R$ "},
+	{Code: "EEK", Rate: 31, Separator: ",", Suffix: " EEK"},
+	{Code: "LTL", Rate: 4, Separator: ",", Suffix: " Lt"},
+	{Code: "KRW", Rate: 1850, Separator: ",", Prefix: "₩"},
+	{Code: "ZAR", Rate: 13, Separator: ",", Prefix: "R "},
+	{Code: "CUSTOM", Rate: 1, Separator: ","},
+	{Code: "GEL", Rate: 3, Separator: ",", Suffix: " GEL"},
+	{Code: "IRR", Rate: 4901, Separator: ",", Suffix: " Rls"},
+	{Code: "RUB", Rate: 80, Separator: ",", Suffix: " rub"},
+	{Code: "MXN", Rate: 24, Separator: ",", Prefix: "$"},
+	{Code: "NTD", Rate: 40, Separator: ",", Prefix: "NTD "},
+	{Code: "CNY", Rate: 8, Separator: ",", Prefix: "¥"},
+	{Code: "HKD", Rate: 10, Separator: ",", Prefix: "HKD "},
+	{Code: "INR", Rate: 90, Separator: ",", Prefix: "₹"},
+}
+
+// Commonly used currencies, provided for convenience.
+var (
+	CurrencyGBP = Currencies[0]
+	CurrencyUSD = Currencies[1]
+	CurrencyEUR = Currencies[2]
+	CurrencyJPY = Currencies[3]
+)
+
+// GetCurrency returns the built-in currency with the given code (e.g "USD").
+// The second return value is false if no such currency exists.
+func GetCurrency(code string) (Currency, bool) {
+	for _, c := range Currencies {
+		if strings.EqualFold(c.Code, code) {
+			return c, true
+		}
+	}
+	return Currency{}, false
+}
+
+// In converts the amount into the given currency, using its exchange rate.
+func (m Money) In(c Currency) int64 {
+	return int64(m) * c.Rate
+}
+
+// Format returns the amount as it would be displayed in-game in the given currency, e.g "-$1,234".
+func (m Money) Format(c Currency) string {
+	v := m.In(c)
+
+	var b strings.Builder
+	if v < 0 {
+		b.WriteString("-")
+	}
+	b.WriteString(c.Prefix)
+
+	digits := strconv.FormatInt(v, 10)
+	digits = strings.TrimPrefix(digits, "-")
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(c.Separator)
+		}
+		b.WriteRune(d)
+	}
+
+	b.WriteString(c.Suffix)
+	return b.String()
+}
+
+// String returns the amount formatted in Pounds Sterling, OpenTTD's base currency.
+func (m Money) String() string {
+	return m.Format(CurrencyGBP)
+}
+
+// MarshalJSON encodes the amount as a plain JSON number, in the base currency.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(m), 10)), nil
+}
+
+// UnmarshalJSON decodes an amount from a JSON number.
+// Quoted numbers are also accepted, as some consumers (e.g JavaScript) can't represent 64 bit integers safely.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	var v int64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*m = Money(v)
+	return nil
+}
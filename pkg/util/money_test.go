@@ -0,0 +1,34 @@
+package util
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMoneyFormat(t *testing.T) {
+	assert.Equal(t, "£0", Money(0).String())
+	assert.Equal(t, "£123", Money(123).String())
+	assert.Equal(t, "£1,234,567", Money(1234567).String())
+	assert.Equal(t, "-£1,000", Money(-1000).String())
+	assert.Equal(t, "$2,000", Money(1000).Format(CurrencyUSD))
+	assert.Equal(t, "¥220,000", Money(1000).Format(CurrencyJPY))
+
+	dkk, ok := GetCurrency("dkk")
+	assert.True(t, ok)
+	assert.Equal(t, "-11,000 kr", Money(-1000).Format(dkk))
+}
+
+func TestMoneyJSON(t *testing.T) {
+	// A company in debt must not wrap around to a huge unsigned number.
+	var m Money
+	assert.NoError(t, json.Unmarshal([]byte("-5000"), &m))
+	assert.Equal(t, Money(-5000), m)
+
+	assert.NoError(t, json.Unmarshal([]byte(`"12345"`), &m))
+	assert.Equal(t, Money(12345), m)
+
+	b, err := json.Marshal(Money(-42))
+	assert.NoError(t, err)
+	assert.Equal(t, "-42", string(b))
+}
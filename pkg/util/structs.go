@@ -24,17 +24,22 @@ type OpenttdTypeCounts struct {
 	Ship uint16 `json:"ship"`
 }
 
+// Total returns the sum of all the counts.
+func (c OpenttdTypeCounts) Total() int {
+	return int(c.Train) + int(c.Truck) + int(c.Bus) + int(c.Aircraft) + int(c.Ship)
+}
+
 type OpenttdCompany struct {
 	// The company name.
 	Name string `json:"name"`
 	// The year the company was first founded.
 	YearStart uint32 `json:"start_year"`
-	// The value of the company, in credits.
-	Value uint64 `json:"value"`
-	// The amount of disposable cash the company has.
-	Money uint64 `json:"cash"`
+	// The value of the company.
+	Value Money `json:"value"`
+	// The amount of disposable cash the company has. Can go negative if they're in debt.
+	Money Money `json:"cash"`
 	// The company's current income.
-	Income int64 `json:"income"`
+	Income Money `json:"income"`
 	// The company's performance index. Maximum score of 1000.
 	Performance uint16 `json:"performance"`
 	// Whether the company has a password set.
@@ -0,0 +1,168 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A Tile is the index of a tile on the map, as used by the game (e.g in CmdLogging events).
+// Tiles are numbered row by row from the north corner, so converting one to X/Y coordinates needs the map width:
+// use MapSize, or Tile.XY().
+type Tile uint32
+
+// XY returns the coordinates of the tile on a map of the given width.
+func (t Tile) XY(mapWidth uint16) TileXY {
+	if mapWidth == 0 {
+		return TileXY{}
+	}
+	return TileXY{X: uint32(t) % uint32(mapWidth), Y: uint32(t) / uint32(mapWidth)}
+}
+
+// TileXY is the position of a tile on the map.
+// X runs from the north corner to the west, Y from the north corner to the east.
+type TileXY struct {
+	X uint32 `json:"x"`
+	Y uint32 `json:"y"`
+}
+
+// ParseTileXY parses coordinates in the "x,y" format returned by TileXY.String().
+func ParseTileXY(s string) (p TileXY, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return p, ErrInvalidTile
+	}
+	x, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 32)
+	if err != nil {
+		return p, ErrInvalidTile
+	}
+	y, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32)
+	if err != nil {
+		return p, ErrInvalidTile
+	}
+	return TileXY{X: uint32(x), Y: uint32(y)}, nil
+}
+
+// String returns the coordinates in the form "x,y".
+func (p TileXY) String() string {
+	return fmt.Sprintf("%d,%d", p.X, p.Y)
+}
+
+// Tile returns the index of the tile at these coordinates on a map of the given width.
+func (p TileXY) Tile(mapWidth uint16) Tile {
+	return Tile(p.Y*uint32(mapWidth) + p.X)
+}
+
+// Manhattan returns the distance between two tiles in tiles travelled along the X and Y axes.
+// This is the distance the game uses for most things, e.g station spread and catchment.
+func (p TileXY) Manhattan(q TileXY) uint32 {
+	return absDiff(p.X, q.X) + absDiff(p.Y, q.Y)
+}
+
+// Euclidean returns the straight line distance between two tiles.
+func (p TileXY) Euclidean(q TileXY) float64 {
+	dx := float64(absDiff(p.X, q.X))
+	dy := float64(absDiff(p.Y, q.Y))
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+// absDiff returns the absolute difference between a and b.
+func absDiff(a, b uint32) uint32 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// A TileArea is a rectangle of tiles, from Min to Max inclusive.
+type TileArea struct {
+	Min TileXY `json:"min"`
+	Max TileXY `json:"max"`
+}
+
+// BoundingBox returns the smallest area containing all of the given tiles.
+func BoundingBox(points ...TileXY) (a TileArea) {
+	for i, p := range points {
+		if i == 0 {
+			a = TileArea{Min: p, Max: p}
+			continue
+		}
+		a.Min.X = minUint32(a.Min.X, p.X)
+		a.Min.Y = minUint32(a.Min.Y, p.Y)
+		a.Max.X = maxUint32(a.Max.X, p.X)
+		a.Max.Y = maxUint32(a.Max.Y, p.Y)
+	}
+	return a
+}
+
+// Contains returns whether the tile is within the area.
+func (a TileArea) Contains(p TileXY) bool {
+	return p.X >= a.Min.X && p.X <= a.Max.X && p.Y >= a.Min.Y && p.Y <= a.Max.Y
+}
+
+// Width returns the size of the area along the X axis, in tiles.
+func (a TileArea) Width() uint32 {
+	return a.Max.X - a.Min.X + 1
+}
+
+// Height returns the size of the area along the Y axis, in tiles.
+func (a TileArea) Height() uint32 {
+	return a.Max.Y - a.Min.Y + 1
+}
+
+// String returns the area in the form "x,y-x,y".
+func (a TileArea) String() string {
+	return a.Min.String() + "-" + a.Max.String()
+}
+
+func minUint32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// MapSize is the size of a map in tiles, which is needed to make sense of Tile indexes.
+type MapSize struct {
+	Width  uint16 `json:"width"`
+	Height uint16 `json:"height"`
+}
+
+// Tiles returns the number of tiles on the map.
+func (m MapSize) Tiles() uint32 {
+	return uint32(m.Width) * uint32(m.Height)
+}
+
+// Contains returns whether the tile is on the map.
+func (m MapSize) Contains(t Tile) bool {
+	return uint32(t) < m.Tiles()
+}
+
+// ContainsXY returns whether the coordinates are on the map.
+func (m MapSize) ContainsXY(p TileXY) bool {
+	return p.X < uint32(m.Width) && p.Y < uint32(m.Height)
+}
+
+// XY returns the coordinates of the tile, or ErrInvalidTile if it isn't on the map.
+func (m MapSize) XY(t Tile) (TileXY, error) {
+	if !m.Contains(t) {
+		return TileXY{}, ErrInvalidTile
+	}
+	return t.XY(m.Width), nil
+}
+
+// Tile returns the tile at the coordinates, or ErrInvalidTile if they aren't on the map.
+func (m MapSize) Tile(p TileXY) (Tile, error) {
+	if !m.ContainsXY(p) {
+		return 0, ErrInvalidTile
+	}
+	return p.Tile(m.Width), nil
+}
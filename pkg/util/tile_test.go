@@ -0,0 +1,39 @@
+package util
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTile(t *testing.T) {
+	m := MapSize{Width: 256, Height: 128}
+
+	p, err := m.XY(Tile(3*256 + 10))
+	assert.NoError(t, err)
+	assert.Equal(t, TileXY{X: 10, Y: 3}, p)
+	assert.Equal(t, "10,3", p.String())
+
+	tile, err := m.Tile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, Tile(3*256+10), tile)
+
+	// Tiles off the map are rejected
+	_, err = m.XY(Tile(256 * 128))
+	assert.Equal(t, ErrInvalidTile, err)
+	_, err = m.Tile(TileXY{X: 256, Y: 0})
+	assert.Equal(t, ErrInvalidTile, err)
+
+	q, err := ParseTileXY("13, 7")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(7), p.Manhattan(q))
+	assert.Equal(t, 5.0, p.Euclidean(q))
+	_, err = ParseTileXY("13")
+	assert.Equal(t, ErrInvalidTile, err)
+
+	area := BoundingBox(p, q, TileXY{X: 11, Y: 1})
+	assert.Equal(t, "10,1-13,7", area.String())
+	assert.Equal(t, uint32(4), area.Width())
+	assert.Equal(t, uint32(7), area.Height())
+	assert.True(t, area.Contains(TileXY{X: 12, Y: 5}))
+	assert.False(t, area.Contains(TileXY{X: 9, Y: 5}))
+}
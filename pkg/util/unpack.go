@@ -107,9 +107,9 @@ func (server *OpenttdServerState) PopulateCompanyState(buf *bytes.Buffer) {
 			company.Name = string(bytes.Trim(rawCompanyName, "\x00"))
 
 			company.YearStart = binary.LittleEndian.Uint32(buf.Next(4))
-			company.Value = binary.LittleEndian.Uint64(buf.Next(8))
-			company.Money = binary.LittleEndian.Uint64(buf.Next(8))
-			binary.Read(buf, binary.LittleEndian, &company.Income)
+			company.Value = Money(binary.LittleEndian.Uint64(buf.Next(8)))
+			company.Money = Money(binary.LittleEndian.Uint64(buf.Next(8)))
+			company.Income = Money(binary.LittleEndian.Uint64(buf.Next(8)))
 			company.Performance = binary.LittleEndian.Uint16(buf.Next(2))
 			company.Passworded = !(int(buf.Next(1)[0]) == 0)
 